@@ -1,12 +1,13 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"sort"
-	"strings"
+	"time"
 
 	"svtc-sync/pkg/helpers"
 	"svtc-sync/pkg/models"
@@ -19,48 +20,94 @@ import (
 // --------------------------------------------------------------------------------------------
 
 type Configuration struct {
-	DBfile  string // SQL database reference file
-	Source  string // Source data to check against master Member reference
-	Output  string // NF (not Found), Expired status, Duplicates or nil
-	Expire  string // Date in the format M/D/YY to filter out earlier expire dates
-	Email   bool   // Emails only formatted with delimiter
-	Actives bool   // Get active member update from ClubExpress and sync with reference data
-	Raw     bool   // Output raw JSON records as read from ClubExpress API JSON file
-	Preview bool   // Option to only preview results of active member sync, ie not commit to DB
+	DBfile   string   // SQL database reference file
+	Source   string   // Source data to check against master Member reference
+	Output   string   // NF (not Found), Duplicates, a status option (e.g. EXP, see models.StatusMap) or nil
+	Expire   string   // Date in the format M/D/YY to filter out earlier expire dates
+	Email    bool     // Emails only formatted with delimiter
+	Actives  bool     // Get active member update from ClubExpress and sync with reference data
+	Raw      bool     // Output raw JSON records as read from ClubExpress API JSON file
+	Preview  bool     // Option to only preview results of active member sync, ie not commit to DB
+	Recent   int      // Number of days within which contacted individuals are suppressed (or marked) in check output
+	Mark     bool     // Mark instead of suppress recently contacted individuals in check output
+	Via      string   // Channel of an outreach record, e.g. email, slack, phone
+	Reason   string   // Reason (or name of the template) of an outreach record
+	Tmpl     string   // Template file of composed, sent and Slack messages
+	Notes    string   // Free form notes of an outreach record or member edit
+	Date     string   // Date of an outreach record in the format YYYY-MM-DD, defaults to today
+	Args     []string // Arguments following the source operator, e.g. for outreach add
+	From     string   // Sender address of composed messages, e.g. "SVTC <membership@svtc.org>"
+	Eml      string   // Directory to write composed messages to as individual .eml files
+	Mbox     string   // File to write composed messages to in mbox format
+	SMTP     string   // SMTP relay host:port without authentication, overrides the SMTP credentials file
+	Rate     int      // Maximum number of messages sent per minute
+	Post     string   // Slack channel to post a summary of an actives sync or platform check to
+	CSV      bool     // Attach the full report as CSV file to the posted summary
+	Snapshot bool     // Store a snapshot of the results of an unfiltered platform check
+	Addr     string   // Network address of the http server receiving Slack requests, e.g. ":8080"
+	Max      int      // Maximum number of changes applied to a Slack user group or channel
+	Grace    int      // Number of days after expiration before the Slack policy applies to a user
+	Runs     int      // Number of days with check runs a user must be Not Found on before the Slack policy applies
+	Wait     int      // Number of days after a policy action before the next action is taken
+	Action   string   // Maximum policy action, i.e. warn, guest or deactivate
+	SlackAPI string   // Slack web api base URL, e.g. of a local stand-in for testing
+	Redirect string   // OAuth redirect URL, defaults to the localhost callback server of the auth command
+	Store    string   // Credential store, i.e. file, env or crypt
+	Secrets  string   // Directory of the file and crypt credential stores
+	File     string   // Actives JSON file (gzipped or plain) or checksum of an archived version to sync from
+	Archive  string   // Directory of archived versions of the actives JSON file
+	Drop     int      // Maximum drop in percent of the actives count from the last sync, before the sync is refused
+	Verify   bool     // Verify Slack users Not Found or Expired with the ClubExpress member status api
+	Expiry   string   // Expiration policy file (JSON) with rules by membership type, end of calendar year if not set
+	Format   string   // Output format of reports, i.e. text, json or csv
+	Sort     string   // Comma separated columns to sort search results by, "-" prefix for descending order
+	Limit    int      // Maximum number of search results, 0 for no limit
+	PII      bool     // Include member emails in reports, masked otherwise
 }
 
 type Application struct {
 	ErrorLog         *log.Logger
 	InfoLog          *log.Logger
+	Ctx              context.Context // Cancelled on Ctrl-C, stops api requests and long running operations
 	Config           *Configuration
-	Creds            *api.CredsModel         // API Credentials
-	MemberSQL        *sqlite.MemberModel     // SVTC ClubExpress based SQL DB reference data
-	ExpressMemberAPI *api.ExpressMemberModel // ClubExpress API member data
-	StravaAthleteAPI *api.StravaAthleteModel // Strava Club API athlete object
-	SlackMemberAPI   *api.SlackMemberModel   // Slack Web API workspace member data
+	Creds            *api.CredsModel            // API Credentials
+	MemberSQL        *sqlite.MemberModel        // SVTC ClubExpress based SQL DB reference data
+	OutreachSQL      *sqlite.OutreachModel      // Log of outreach to members and platform users
+	DoNotContactSQL  *sqlite.DoNotContactModel  // Email addresses that must not be sent reminders
+	RunSQL           *sqlite.RunModel           // Snapshots of platform check results
+	ActionSQL        *sqlite.ActionModel        // Log of Slack policy actions
+	FeedSQL          *sqlite.FeedModel          // Archived versions of the ClubExpress actives JSON file
+	ExpressStatusSQL *sqlite.ExpressStatusModel // Cached results of the ClubExpress member status api
+	MergeSQL         *sqlite.MergeModel         // Duplicate member records merged into the record kept
+	MemberEditSQL    *sqlite.MemberEditModel    // Log of edits of single member records
+	ExpressMemberAPI *api.ExpressMemberModel    // ClubExpress API member data
+	StravaAthleteAPI *api.StravaAthleteModel    // Strava Club API athlete object
+	SlackMemberAPI   *api.SlackMemberModel      // Slack Web API workspace member data
+	MailAPI          *api.MailModel             // SMTP relay to send reminder emails
 }
 
 // --------------------------------------------------------------------------------------------
 
 func (app *Application) ActivesRaw() error {
 
-	// Get Header information of http file Get Request and print key / value pairs
-	vals, err := app.ExpressMemberAPI.GetHeader()
-	if err != nil {
-		app.ErrorLog.Printf("[GetHeader] %s", err)
-		return err
-	}
-	for k, v := range vals {
-		fmt.Printf("[%s] %s \n", k, v)
+	// Fetch the ClubExpress API JSON file (unconditionally), or load it from a local or archived file
+	var f *models.Feed
+	var err error
+	if app.Config.File != "" {
+		f, err = app.loadFeed(app.Config.File)
+	} else {
+		f, err = app.ExpressMemberAPI.FetchActives(app.Ctx, "", "")
 	}
-
-	// Retrieve and print  raw data of active members from ClubExpress API JSON file
-	data, err := app.ExpressMemberAPI.GetActivesRaw()
 	if err != nil {
 		app.ErrorLog.Printf("[GetActives] %s", err)
 		return err
 	}
-	fmt.Printf("%s", string(data))
+
+	// Print file information and raw data of active members
+	fmt.Printf("[Last-Modified] %s \n", f.LastModified)
+	fmt.Printf("[ETag] %s \n", f.ETag)
+	fmt.Printf("[SHA-256] %s \n", f.Checksum)
+	fmt.Printf("%s", string(f.Data))
 
 	return nil
 
@@ -70,21 +117,54 @@ func (app *Application) ActivesRaw() error {
 
 func (app *Application) ActivesSync() error {
 
-	// Get Header of http request and print file date: [Last-Modified][Fri, 24 Feb 2023 11:00:04 GMT]
-	vals, err := app.ExpressMemberAPI.GetHeader()
+	// Fetch the JSON file if changed since the last sync, or load it from a local or archived file
+	f, err := app.activesFeed()
 	if err != nil {
-		app.ErrorLog.Printf("[GetHeader] %s", err)
 		return err
 	}
-	app.InfoLog.Printf("[ActivesSync] JSON File Date: %s \n", vals["Last-Modified"][0])
+	if f == nil {
+		return nil
+	}
 
 	// Get list of active members from ClubExpress API JSON file
-	mlJSON, err := app.ExpressMemberAPI.GetActives()
+	mlJSON, bad, err := app.ExpressMemberAPI.ParseActives(f.Data)
 	if err != nil {
-		app.ErrorLog.Printf("[GetActives] %s", err)
+		app.ErrorLog.Printf("[ParseActives] %s", err)
 		return err
 	}
-	app.InfoLog.Printf("[ActivesSync] Created list of %d Active club members from JSON File", len(mlJSON))
+
+	// Validate the records before any change is applied, rejected records are reported and skipped
+	mlJSON, rejected := validateActives(mlJSON)
+	for _, e := range bad {
+		r := &activesReject{Reason: e.Error()}
+		var re *api.RecordError
+		if errors.As(e, &re) {
+			r.Num, r.Name, r.Reason = re.Num, fmt.Sprintf("record at index %d", re.Index), re.Err.Error()
+		}
+		rejected = append(rejected, r)
+	}
+	f.Count = len(mlJSON)
+	app.InfoLog.Printf("[ActivesSync] Created list of %d Active club members from JSON File, %d records rejected", len(mlJSON), len(rejected))
+
+	for _, r := range rejected {
+		fmt.Printf("[%s] %s rejected: %s \n", r.Num, r.Name, r.Reason)
+	}
+
+	// Refuse to sync a file that is empty or truncated, i.e. with far fewer members than the last synced version
+	err = app.checkActivesCount(f.Count)
+	if err != nil {
+		app.ErrorLog.Printf("[ActivesSync] %s", err)
+		return err
+	}
+
+	// Archive a newly fetched version of the file before it is synced
+	if !app.Config.Preview && app.Config.File == "" {
+		err = app.archiveFeed(f)
+		if err != nil {
+			app.ErrorLog.Printf("[archiveFeed] %s", err)
+			return err
+		}
+	}
 
 	// Iterate over list and compare records to DB, output differences
 	app.InfoLog.Printf("[ActivesSync] Comparing list of active members to DB")
@@ -94,59 +174,148 @@ func (app *Application) ActivesSync() error {
 		app.InfoLog.Printf("[ActivesSync] Preview flag set: NOT making changes to DB \n")
 	}
 
-	// Get last day of the year to be used for new and updated active member expired date
-	dstr := helpers.GetLastDateStr()
-	app.InfoLog.Printf("[ActivesSync] Expired dates will be set to last day of this year \n\n")
+	// Expiration policy that determines the expired date of new and updated active members by membership type
+	policy, err := app.expirePolicy()
+	if err != nil {
+		app.ErrorLog.Printf("[expirePolicy] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[ActivesSync] Expired dates will be set per expiration policy: %s, %d membership types \n\n", policy.Default, len(policy.Types))
+	now := time.Now().Local()
+
+	// Summary of the sync, to be posted to Slack if requested
+	summary := &Summary{
+		Title:  "ClubExpress actives sync",
+		Counts: map[string]int{},
+		New:    []string{},
+		Header: []string{"num", "name", "old status", "new status", "expired", "result"},
+	}
+	if app.Config.Preview {
+		summary.Title += " (preview)"
+	}
+
+	for _, r := range rejected {
+		summary.Errors++
+		summary.Rows = append(summary.Rows, []string{r.Num, r.Name, "", "", "", "rejected: " + r.Reason})
+	}
+
+	// Member numbers of merged duplicate records, whose imports update the record kept instead
+	redirects, err := app.MergeSQL.Redirects()
+	if err != nil {
+		app.ErrorLog.Printf("[Merge SQL] %s", err)
+		return err
+	}
+
+	// Records of the last synced version, an active member's expired date is only extended if its dates changed
+	last, err := app.lastActives()
+	if err != nil {
+		return err
+	}
 
 	for _, m := range mlJSON {
 
-		// Select member record by JSON file's member number.
-		// If number not found, create and assign a new member record with Status as New as result
+		name := m.FirstName + " " + m.LastName
+		dstr := policy.expires(m, now)
+		renewal := renewed(m, policy.rule(m.Type), last)
+
+		if num, ok := redirects[m.Num]; ok {
+			app.InfoLog.Printf("[ActivesSync] Member %s was merged, updating %s instead", m.Num, num)
+			m.Num = num
+		}
+
+		// Select member record by JSON file's member number. If number not found, insert a new member record.
 		mSQL, err := app.MemberSQL.Get(m.Num)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				mSQL = &models.MemberSVTC{Num: m.Num, Status: "New"}
-			} else {
-				app.ErrorLog.Printf("[Get] %s", err)
-				return err
-			}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			app.ErrorLog.Printf("[Get] %s", err)
+			return err
 		}
 
-		// Insert New member record with Status Active and end of year as expired date
-		if mSQL.Status == "New" {
+		// Insert new member record with Status Active and the expired date of the expiration policy
+		if mSQL == nil {
 
 			if app.Config.Preview {
-				fmt.Printf("[%s] %s %s (New) -> (Active) %s \n", m.Num, m.FirstName, m.LastName, dstr)
+				fmt.Printf("[%s] %s (-) -> (Active) %s \n", m.Num, name, dstr)
 			} else {
-				m.Status = "Active"
+				m.Status = models.StatusActive
 				m.Active = true
 				m.Expired = dstr
 				err = app.MemberSQL.Insert(m)
 				if err != nil {
 					app.ErrorLog.Printf("[Insert] %s", err)
+					summary.Errors++
+					summary.Rows = append(summary.Rows, []string{m.Num, name, "", "Active", dstr, err.Error()})
 					continue
 				}
 				app.InfoLog.Printf("[ActivesSync] Inserted new club member with status Active: %s", m.Num)
 			}
 
+			if app.Config.Preview {
+				summary.Rows = append(summary.Rows, []string{m.Num, name, "", "Active", dstr, "would insert"})
+				continue
+			}
+			summary.New = append(summary.New, fmt.Sprintf("[%s] %s", m.Num, name))
+			summary.Rows = append(summary.Rows, []string{m.Num, name, "", "Active", dstr, "inserted"})
+
 			continue
 		}
 
-		// Update existing non-active member record to reflect Active status and set expired date
-		if mSQL.Status != "Active" {
+		// Update existing non-active member record to reflect Active status and set expired date, or extend the
+		// expired date of an active member that renewed per the JSON file. Status changes that are not allowed by
+		// the member lifecycle are reported, and the record is left unchanged.
+		if mSQL.Status != models.StatusActive || (renewal && dstr > mSQL.Expired) {
 
 			if app.Config.Preview {
-				fmt.Printf("[%s] %s %s (%s) -> (Active) %s \n", m.Num, m.FirstName, m.LastName, mSQL.Status, dstr)
+				err = mSQL.Status.Transition(models.StatusActive)
+				if err == nil {
+					fmt.Printf("[%s] %s (%s) -> (Active) %s \n", m.Num, name, mSQL.Status, dstr)
+				}
 			} else {
-				err = app.MemberSQL.UpdateStatus(m.Num, "Active", dstr)
-				if err != nil {
-					app.ErrorLog.Printf("[UpdateStatus] %s", err)
-					continue
+				err = app.MemberSQL.UpdateStatus(m.Num, models.StatusActive, dstr)
+				if err == nil {
+					app.InfoLog.Printf("[ActivesSync] Updated club member. Set status to Active, expires %s: %s", dstr, m.Num)
 				}
-				app.InfoLog.Printf("[ActivesSync] Updated club member. Set status to Active: %s", m.Num)
+			}
+			if err != nil {
+				app.ErrorLog.Printf("[UpdateStatus] %s", err)
+				fmt.Printf("[%s] %s (%s) -> (Active) rejected: %s \n", m.Num, name, mSQL.Status, err)
+				summary.Errors++
+				summary.Rows = append(summary.Rows, []string{m.Num, name, string(mSQL.Status), "Active", dstr, err.Error()})
+				continue
 			}
 
-			// continue
+			result := "updated"
+			if app.Config.Preview {
+				result = "would update"
+			}
+			summary.Rows = append(summary.Rows, []string{m.Num, name, string(mSQL.Status), "Active", dstr, result})
+		}
+
+	}
+
+	// Record the synced version, so that the next sync is skipped unless the file changes
+	if !app.Config.Preview && app.Config.File == "" {
+		err = app.FeedSQL.Insert(f)
+		if err != nil {
+			app.ErrorLog.Printf("[Feed SQL] %s", err)
+			return err
+		}
+	}
+
+	// Post summary of the sync to Slack if requested, incl. the number of DB records by status
+	if app.Config.Post != "" {
+
+		ml, err := app.MemberSQL.ListMembers()
+		if err != nil {
+			app.ErrorLog.Printf("[ListMembers SQL] %s", err)
+			return err
+		}
+		for _, m := range ml {
+			summary.Counts[string(m.Status)]++
+		}
+
+		err = app.postSummary(summary)
+		if err != nil {
+			return err
 		}
 
 	}
@@ -198,115 +367,91 @@ func (app *Application) ListMembers() error {
 
 func (app *Application) CheckSlackMembers() error {
 
-	// Read Slack Bot Access Token from credentials file.
-	slack_access_token, err := app.Creds.GetSlackAccess()
+	mtl, err := app.MatchSlackMembers()
 	if err != nil {
-		app.ErrorLog.Printf("[ReadSlackAccess] Unable to read Slack bot credentials %s", err)
 		return err
 	}
-	app.InfoLog.Printf("[CheckSlackMembers] Retrieved Slack api access token from file \n")
 
-	// Get list of Slack team members of workspace that app is installed in
-	mlSlack, err := app.SlackMemberAPI.List(slack_access_token)
+	// Summarize results, and store a snapshot for comparison with future runs if requested
+	summary, err := app.summarize("slack", mtl, app.Config.Snapshot)
 	if err != nil {
-		app.ErrorLog.Printf("[ListUsers] %s", err)
 		return err
 	}
-	app.InfoLog.Printf("[CheckSlackMembers] Requested list of %d workspace users from Slack web api", len(mlSlack))
-
-	// Sort workspace user list by first name (ignore upper / lowercase)
-	app.SlackMemberAPI.Sort(mlSlack)
-	app.InfoLog.Printf("[CheckSlackMembers] Sorted workspace user list alphabetically by firstname")
 
 	// Log output type and format as appropriate
 	app.InfoLog.Printf("[CheckSlackMembers] Generating %s output of matches with %s \n\n", app.Config.Output, app.Config.DBfile)
 
-	// Iterate over list of Slack workspace users/members and check against reference member DB
-	for _, mSlack := range mlSlack {
-
-		// Ignore bot or app records (this flag is set to false for those in Slack)
-		if !mSlack.Is_Email_Confirmed {
-			continue
-		}
-
-		// Populate a new search member struct with query criteria
-		ms := models.MemberSVTC{
-			FirstName: strings.ToLower(mSlack.Profile.FirstName),
-			LastName:  strings.ToLower(mSlack.Profile.LastName),
-			Email:     strings.ToLower(mSlack.Profile.Email),
-			Status:    models.StatusMap[app.Config.Output],
-			Expired:   app.Config.Expire,
-		}
+	for _, mt := range mtl {
+		app.printMatch(mt)
+	}
 
-		// Query list of members from Sqlite3 DB using query criteria according to (firstname AND lastname) OR email
-		ml, err := app.MemberSQL.ListMatch("slack", &ms)
+	// Verify users Not Found or Expired with the ClubExpress member status api if requested
+	if app.Config.Verify {
+		err = app.verifyExpress(mtl, summary)
 		if err != nil {
-			app.ErrorLog.Printf("[ListMembers SQL] %s", err)
 			return err
 		}
+	}
 
-		// Query alias table for members using same search criteria
-		ma, err := app.MemberSQL.GetAlias(&ms)
+	// Post summary of the check to Slack if requested
+	if app.Config.Post != "" {
+		err = app.postSummary(summary)
 		if err != nil {
-			app.ErrorLog.Printf("[Alias SQL] %s", err)
 			return err
 		}
+	}
 
-		// Append matches from alias table to result set
-		ml = append(ml, ma...)
-
-		// Sort results of comparison by expiration date
-		app.sort(ml, "exp")
+	return nil
 
-		// Determine output based on configuration settings and print results of comnparison
-		switch app.Config.Output {
+}
 
-		case "NF":
+// --------------------------------------------------------------------------------------------
 
-			// Print record not found in reference data
-			if len(ml) == 0 {
-				fmt.Printf("[%s %s (%s)] Not Found \n", mSlack.Profile.FirstName, mSlack.Profile.LastName, mSlack.Profile.Email)
-			}
+func (app *Application) MatchSlackMembers() ([]*Match, error) {
 
-		case "DUP":
+	// Get list of Slack team members of workspace that app is installed in. The bot access token is read from
+	// the credential store by the api client.
+	mlSlack, err := app.SlackMemberAPI.List(app.Ctx)
+	if err != nil {
+		app.ErrorLog.Printf("[ListUsers] %s", err)
+		return nil, err
+	}
+	app.InfoLog.Printf("[MatchSlackMembers] Requested list of %d workspace users from Slack web api", len(mlSlack))
 
-			if len(ml) > 1 {
-				// Print all records where there is more than one match, gouped by Slack User record
-				fmt.Printf("[%s %s (%s)] \n", mSlack.Profile.FirstName, mSlack.Profile.LastName, mSlack.Profile.Email)
-				for _, m := range ml {
-					fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
-				}
-			}
+	// Sort workspace user list by first name (ignore upper / lowercase)
+	app.SlackMemberAPI.Sort(mlSlack)
+	app.InfoLog.Printf("[MatchSlackMembers] Sorted workspace user list alphabetically by firstname")
 
-		case "EXP", "ACT", "TRI":
+	// Get outreach records within the configured window to mark or suppress recently contacted users
+	contacts, err := app.recentOutreach()
+	if err != nil {
+		app.ErrorLog.Printf("[recentOutreach] %s", err)
+		return nil, err
+	}
 
-			// Print records that have a non-active status. When email flag is set, print in RFC 5322 format
-			if len(ml) > 0 {
-				if !app.Config.Email {
-					fmt.Printf("[%s %s (%s)] \n", mSlack.Profile.FirstName, mSlack.Profile.LastName, mSlack.Profile.Email)
-				}
-				for _, m := range ml {
-					if app.Config.Email {
-						fmt.Printf("%s %s <%s>,\n", m.FirstName, m.LastName, m.Email)
-					} else {
-						fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
-					}
-				}
-			}
+	mtl := []*Match{}
 
-		default:
+	// Iterate over list of Slack workspace users/members and check against reference member DB
+	for _, mSlack := range mlSlack {
 
-			// Print all records (incl. duplicates and not found) grouped by Slack User record
-			fmt.Printf("[%s %s (%s)] \n", mSlack.Profile.FirstName, mSlack.Profile.LastName, mSlack.Profile.Email)
-			for _, m := range ml {
-				fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
-			}
+		// Ignore bot or app records (this flag is set to false for those in Slack), and deactivated users, that
+		// can not be invited to a channel or user group, nor be acted on by the Slack policy
+		if !mSlack.Is_Email_Confirmed || mSlack.Is_Bot || mSlack.Deleted {
+			continue
+		}
 
+		mt, err := app.matchSlack(mSlack)
+		if err != nil {
+			return nil, err
 		}
 
+		mt.Contact = contacts.find(mt)
+
+		mtl = append(mtl, mt)
+
 	}
 
-	return nil
+	return mtl, nil
 
 }
 
@@ -314,119 +459,85 @@ func (app *Application) CheckSlackMembers() error {
 
 func (app *Application) CheckStravaMembers() error {
 
-	// Check Strava Access Token expiration date. Request new one if expired.
-	app.InfoLog.Printf("[CheckStravaMembers] Check for expiration of Strava api access token \n")
-	strava_access_token, err := app.Creds.CheckStravaExp()
-	if err != nil {
-		app.ErrorLog.Printf("[CheckStravaExp] refresh Strava authorization failed %s", err)
-		return err
-	}
-
-	// Get club information from Strava to obtain member count
-	cStrava, err := app.StravaAthleteAPI.GetClub(strava_access_token)
+	mtl, err := app.MatchStravaMembers()
 	if err != nil {
-		app.ErrorLog.Printf("[Get] %s", err)
 		return err
 	}
-	app.InfoLog.Printf("[CheckStravaMembers] Requested data from Strava api for %s \n", cStrava.Name)
 
-	// Get list of athletes (club members) of Strava club
-	mlStrava, err := app.StravaAthleteAPI.List(cStrava.MemberCount, strava_access_token)
+	// Summarize results, and store a snapshot for comparison with future runs if requested
+	summary, err := app.summarize("strava", mtl, app.Config.Snapshot)
 	if err != nil {
-		app.ErrorLog.Printf("[ListAthletes] %s", err)
 		return err
 	}
-	app.InfoLog.Printf("[CheckStravaMembers] Requested list of %d club athletes from Strava api", len(mlStrava))
-
-	// Sort athlete list (club members) by first name (ignore upper/lower case)
-	app.StravaAthleteAPI.Sort(mlStrava)
-	app.InfoLog.Printf("[CheckStravaMembers] Sorted club athlete list alphabetically by firstname")
 
 	// Log output type and format as appropriate
 	app.InfoLog.Printf("[CheckStravaMembers] Generating %s output of matches with %s \n\n", app.Config.Output, app.Config.DBfile)
 
-	// Iterate over list of Strava club athletes and check if present in reference DB
-	for _, mStrava := range mlStrava {
-
-		// Populate a new member search struct with query criteria
-		ms := models.MemberSVTC{
-			FirstName: strings.ToLower(strings.TrimSpace(mStrava.FirstName)),
-			LastName:  strings.ToLower(strings.TrimSpace(string(mStrava.LastName[0]))),
-			Email:     string('_'),
-			Status:    models.StatusMap[app.Config.Output],
-			Expired:   app.Config.Expire,
-		}
+	for _, mt := range mtl {
+		app.printMatch(mt)
+	}
 
-		// Query list of members from Sqlite3 DB using query criteria according to (firstname AND lastname%)
-		ml, err := app.MemberSQL.ListMatch("strava", &ms)
+	// Post summary of the check to Slack if requested
+	if app.Config.Post != "" {
+		err = app.postSummary(summary)
 		if err != nil {
-			app.ErrorLog.Printf("[ListMembers SQL] %s", err)
 			return err
 		}
+	}
 
-		// Query alias table for members using same criteria
-		ma, err := app.MemberSQL.GetAlias(&ms)
-		if err != nil {
-			app.ErrorLog.Printf("[Alias SQL] %s", err)
-			return err
-		}
+	return nil
 
-		// Append matches from alias table to result set
-		ml = append(ml, ma...)
+}
 
-		// Sort results of comparison by expiration date
-		app.sort(ml, "exp")
+// --------------------------------------------------------------------------------------------
 
-		// Determine output based on configuration settings and print results of comnparison
-		switch app.Config.Output {
+func (app *Application) MatchStravaMembers() ([]*Match, error) {
 
-		case "NF":
+	// Get club information from Strava to obtain member count. The api client refreshes the access token if
+	// expired or rejected.
+	cStrava, err := app.StravaAthleteAPI.GetClub(app.Ctx)
+	if err != nil {
+		app.ErrorLog.Printf("[Get] %s", err)
+		return nil, err
+	}
+	app.InfoLog.Printf("[MatchStravaMembers] Requested data from Strava api for %s \n", cStrava.Name)
 
-			// Print record not found in reference
-			if len(ml) == 0 {
-				fmt.Printf("[%s %s] Not Found \n", mStrava.FirstName, mStrava.LastName)
-				///fmt.Printf("insert into alias (memberid, firstname, lastname, email) values=(NNNN, '%s', '%s', '');\n", mStrava.FirstName, mStrava.LastName)
-			}
+	// Get list of athletes (club members) of Strava club
+	mlStrava, err := app.StravaAthleteAPI.List(app.Ctx, cStrava.MemberCount)
+	if err != nil {
+		app.ErrorLog.Printf("[ListAthletes] %s", err)
+		return nil, err
+	}
+	app.InfoLog.Printf("[MatchStravaMembers] Requested list of %d club athletes from Strava api", len(mlStrava))
 
-		case "DUP":
+	// Sort athlete list (club members) by first name (ignore upper/lower case)
+	app.StravaAthleteAPI.Sort(mlStrava)
+	app.InfoLog.Printf("[MatchStravaMembers] Sorted club athlete list alphabetically by firstname")
 
-			if len(ml) > 1 {
-				// Print all records where there is more than one match, gouped by Strava Athlete record
-				fmt.Printf("[%s %s] \n", mStrava.FirstName, mStrava.LastName)
-				for _, m := range ml {
-					fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
-				}
-			}
+	// Get outreach records within the configured window to mark or suppress recently contacted athletes
+	contacts, err := app.recentOutreach()
+	if err != nil {
+		app.ErrorLog.Printf("[recentOutreach] %s", err)
+		return nil, err
+	}
 
-		case "EXP", "ACT", "TRI":
+	mtl := []*Match{}
 
-			// Print records that have a expired status. When email flag is set, print in RFC 5322 format
-			if len(ml) > 0 {
-				if !app.Config.Email {
-					fmt.Printf("[%s %s] \n", mStrava.FirstName, mStrava.LastName)
-				}
-				for _, m := range ml {
-					if app.Config.Email {
-						fmt.Printf("%s %s <%s>,\n", m.FirstName, m.LastName, m.Email)
-					} else {
-						fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
-					}
-				}
-			}
+	// Iterate over list of Strava club athletes and check if present in reference DB
+	for _, mStrava := range mlStrava {
 
-		default:
+		mt, err := app.matchStrava(mStrava)
+		if err != nil {
+			return nil, err
+		}
 
-			// Print all records (incl. duplicates and not found) grouped by Strava Athlete record
-			fmt.Printf("[%s %s] \n", mStrava.FirstName, mStrava.LastName)
-			for _, m := range ml {
-				fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
-			}
+		mt.Contact = contacts.find(mt)
 
-		}
+		mtl = append(mtl, mt)
 
 	}
 
-	return nil
+	return mtl, nil
 
 }
 
@@ -453,3 +564,16 @@ func (app *Application) sort(ml []*models.MemberSVTC, sortby string) []*models.M
 }
 
 // --------------------------------------------------------------------------------------------
+
+// Wait for the specified duration, e.g. to space out messages. Returns an error if cancelled before, i.e. on Ctrl-C.
+func (app *Application) sleep(d time.Duration) error {
+
+	select {
+	case <-app.Ctx.Done():
+		return app.Ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Priorities of audit findings, in order of urgency
+const (
+	priorityHigh   = "HIGH"
+	priorityMedium = "MEDIUM"
+	priorityLow    = "LOW"
+)
+
+var priorityOrder = map[string]int{priorityHigh: 0, priorityMedium: 1, priorityLow: 2}
+
+// Data quality issue of a member or alias record, with a suggested fix
+type Finding struct {
+	Priority string `json:"priority"`
+	Check    string `json:"check"`
+	Record   string `json:"record"` // e.g. [1234] Dave Scott, or alias 12
+	Issue    string `json:"issue"`
+	Fix      string `json:"fix"`
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Audit the member and alias tables for data quality issues, and output the findings ordered by priority with
+// a suggested fix each, in the format of the format option, i.e. text, json or csv.
+func (app *Application) Audit() error {
+
+	ml, err := app.MemberSQL.ListAll()
+	if err != nil {
+		app.ErrorLog.Printf("[ListAll SQL] %s", err)
+		return err
+	}
+
+	al, err := app.MemberSQL.ListAliases()
+	if err != nil {
+		app.ErrorLog.Printf("[ListAliases SQL] %s", err)
+		return err
+	}
+
+	fl := audit(ml, al, time.Now().Local().Format("2006-01-02"))
+
+	switch app.Config.Format {
+
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(fl)
+		if err != nil {
+			return fmt.Errorf("json encoding of findings failed: %w", err)
+		}
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		err = w.Write([]string{"priority", "check", "record", "issue", "fix"})
+		for _, f := range fl {
+			if err == nil {
+				err = w.Write([]string{f.Priority, f.Check, f.Record, f.Issue, f.Fix})
+			}
+		}
+		w.Flush()
+		if err == nil {
+			err = w.Error()
+		}
+		if err != nil {
+			return fmt.Errorf("write csv failed: %w", err)
+		}
+
+	default:
+		counts := map[string]int{}
+		for _, f := range fl {
+			fmt.Printf("%-6s %-16s %s: %s \n\tfix: %s \n", f.Priority, f.Check, f.Record, f.Issue, f.Fix)
+			counts[f.Priority]++
+		}
+		fmt.Printf("\n%d findings: %d high, %d medium, %d low priority \n", len(fl), counts[priorityHigh], counts[priorityMedium], counts[priorityLow])
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Run the checks over all member records (incl. invalid ones) and alias records as of today (YYYY-MM-DD).
+// Returns the findings ordered by priority, check and record.
+func audit(ml []*models.MemberSVTC, al []*models.MemberAlias, today string) []*Finding {
+
+	fl := []*Finding{}
+	add := func(priority, check, record, issue, fix string) {
+		fl = append(fl, &Finding{Priority: priority, Check: check, Record: record, Issue: issue, Fix: fix})
+	}
+
+	byID := map[int]*models.MemberSVTC{}
+	byEmail := map[string][]*models.MemberSVTC{}
+	byName := map[string][]*models.MemberSVTC{}
+
+	for _, m := range ml {
+
+		byID[m.ID] = m
+		rec := memberRecord(m)
+
+		// Invalid records are kept for reference, but are not checked otherwise
+		if !m.Active {
+			continue
+		}
+
+		email := strings.ToLower(strings.TrimSpace(m.Email))
+		switch {
+		case email == "":
+			add(priorityMedium, "missing-email", rec, "no email", "add the email of the member in ClubExpress and sync, or add it to the record")
+		case !validEmail(email):
+			add(priorityMedium, "invalid-email", rec, fmt.Sprintf("invalid email %q", m.Email), "correct the email of the member in ClubExpress and the record")
+		default:
+			byEmail[email] = append(byEmail[email], m)
+		}
+
+		if name := strings.ToLower(strings.Join(strings.Fields(m.FirstName+" "+m.LastName), " ")); name != "" {
+			byName[name] = append(byName[name], m)
+		}
+
+		if !m.Status.Valid() {
+			add(priorityMedium, "invalid-status", rec, fmt.Sprintf("status %q is not a ClubExpress status", m.Status), "set the status of the ClubExpress member record")
+		}
+
+		for _, d := range []struct{ name, value string }{{"joined", m.Joined}, {"expired", m.Expired}} {
+			switch {
+			case d.value == "":
+				add(priorityLow, "missing-date", rec, fmt.Sprintf("no %s date", d.name), fmt.Sprintf("set the %s date (YYYY-MM-DD) from ClubExpress", d.name))
+			case helpers.GetDate(d.value).IsZero():
+				add(priorityMedium, "malformed-date", rec, fmt.Sprintf("%s date %q is not of the form YYYY-MM-DD, it is treated as 0001-01-01", d.name, d.value), fmt.Sprintf("correct the %s date to YYYY-MM-DD", d.name))
+			}
+		}
+
+		if m.Status == models.StatusActive && !helpers.GetDate(m.Expired).IsZero() && m.Expired < today {
+			add(priorityHigh, "active-expired", rec, fmt.Sprintf("status Active, but expired %s", m.Expired), "sync actives (-actives), or set status Expired if the member did not renew")
+		}
+	}
+
+	// Emails shared by multiple member numbers, which match platform users ambiguously
+	for _, email := range sortedMemberKeys(byEmail) {
+		if mdl := byEmail[email]; len(mdl) > 1 {
+			add(priorityHigh, "shared-email", email, "email of members "+memberNums(mdl), "merge the records if they are the same person (merge keepNum dropNum), otherwise correct the email of all but one")
+		}
+	}
+
+	// The same person under different member numbers, i.e. same name but different emails (shared emails are
+	// reported above)
+	for _, name := range sortedMemberKeys(byName) {
+		mdl := byName[name]
+		if len(mdl) < 2 || sharedEmail(mdl) {
+			continue
+		}
+		add(priorityMedium, "duplicate-person", mdl[0].FirstName+" "+mdl[0].LastName, "same name under members "+memberNums(mdl), "merge the records if they are the same person (merge keepNum dropNum)")
+	}
+
+	for _, a := range al {
+		rec := fmt.Sprintf("alias %d (%s %s %s)", a.ID, a.FirstName, a.LastName, a.Email)
+		m, ok := byID[a.MemberID]
+		switch {
+		case !ok:
+			add(priorityHigh, "orphan-alias", rec, fmt.Sprintf("refers to missing member record id %d", a.MemberID), "delete the alias, or point it at the member's current record")
+		case !m.Active:
+			add(priorityMedium, "inactive-alias", rec, "refers to invalid member record "+memberRecord(m), "point the alias at the member's valid record, or delete it")
+		}
+	}
+
+	sort.SliceStable(fl, func(i, j int) bool {
+		if fl[i].Priority != fl[j].Priority {
+			return priorityOrder[fl[i].Priority] < priorityOrder[fl[j].Priority]
+		}
+		return fl[i].Check < fl[j].Check
+	})
+
+	return fl
+}
+
+// Label of a member record in findings, e.g. [1234] Dave Scott
+func memberRecord(m *models.MemberSVTC) string {
+
+	return fmt.Sprintf("[%s] %s %s", m.Num, m.FirstName, m.LastName)
+}
+
+// Comma separated member numbers in numeric order
+func memberNums(ml []*models.MemberSVTC) string {
+
+	nl := []string{}
+	for _, m := range ml {
+		nl = append(nl, m.Num)
+	}
+	sort.Slice(nl, func(i, j int) bool {
+		a, _ := strconv.Atoi(nl[i])
+		b, _ := strconv.Atoi(nl[j])
+		return a < b
+	})
+
+	return strings.Join(nl, ", ")
+}
+
+// At least two of the members share an email
+func sharedEmail(ml []*models.MemberSVTC) bool {
+
+	seen := map[string]bool{}
+	for _, m := range ml {
+		email := strings.ToLower(strings.TrimSpace(m.Email))
+		if email != "" && seen[email] {
+			return true
+		}
+		seen[email] = true
+	}
+
+	return false
+}
+
+func sortedMemberKeys(m map[string][]*models.MemberSVTC) []string {
+
+	kl := make([]string, 0, len(m))
+	for k := range m {
+		kl = append(kl, k)
+	}
+	sort.Strings(kl)
+
+	return kl
+}
+
+// --------------------------------------------------------------------------------------------
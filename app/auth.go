@@ -0,0 +1,244 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum time to wait for the user to complete the authorization in the browser
+const authTimeout = 5 * time.Minute
+
+// Scope requested for the Strava user, sufficient to read the club and its members
+const stravaScope = "read"
+
+// Bot scopes requested for the Slack app, covering checks, notifications, summaries, groups and the server
+var slackScopes = []string{
+	"users:read", "users:read.email", "chat:write", "im:write", "files:write", "commands",
+	"usergroups:read", "usergroups:write", "channels:read", "channels:manage", "groups:read", "groups:write",
+}
+
+// Result of the OAuth callback, i.e. the authorization code or an error
+type authResult struct {
+	code string
+	err  error
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Obtain the initial credentials of a platform (strava or slack) via its OAuth flow. A callback server is
+// started on localhost, and the authorize URL printed to be opened in a browser. The code passed to the
+// callback is exchanged for the tokens, which are written to the platform creds file.
+func (app *Application) Auth(platform string) error {
+
+	var authorize func(redirect_uri string, state string) (string, error)
+	var exchange func(code string, redirect_uri string) error
+
+	switch platform {
+
+	case "strava":
+		authorize, exchange = app.stravaAuthorize, app.stravaExchange
+
+	case "slack":
+		authorize, exchange = app.slackAuthorize, app.slackExchange
+
+	default:
+		return fmt.Errorf("unsupported platform: %s", platform)
+	}
+
+	// The callback server only listens on the loopback interface, the port is taken from the server address
+	_, port, err := net.SplitHostPort(app.Config.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid server address %s: %w", app.Config.Addr, err)
+	}
+	addr := net.JoinHostPort("localhost", port)
+
+	redirect_uri := app.Config.Redirect
+	if redirect_uri == "" {
+		redirect_uri = "http://" + addr + "/callback"
+	}
+
+	// Random state to tie the callback to this authorization request
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return fmt.Errorf("unable to generate state: %w", err)
+	}
+	state := hex.EncodeToString(b)
+
+	auth_url, err := authorize(redirect_uri, state)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to start callback server: %w", err)
+	}
+
+	result := make(chan authResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+
+		q := r.URL.Query()
+
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			fmt.Fprintf(w, "Authorization failed: %s\n", q.Get("error"))
+			result <- authResult{err: fmt.Errorf("authorization denied: %s", q.Get("error"))}
+		case q.Get("code") == "":
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		default:
+			fmt.Fprintf(w, "Authorization received, you may close this window.\n")
+			result <- authResult{code: q.Get("code")}
+		}
+	})
+
+	srv := &http.Server{
+		Handler:      mux,
+		ErrorLog:     app.ErrorLog,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	app.InfoLog.Printf("[Auth] Waiting for %s authorization on %s \n", platform, redirect_uri)
+	fmt.Printf("Open the following URL in a browser to authorize svtc-sync:\n\n%s\n\n", auth_url)
+
+	var res authResult
+	select {
+	case res = <-result:
+	case <-app.Ctx.Done():
+		return app.Ctx.Err()
+	case <-time.After(authTimeout):
+		return fmt.Errorf("no authorization received within %s", authTimeout)
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	return exchange(res.code, redirect_uri)
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Build the Strava authorize URL, using the client ID of the api credentials file
+func (app *Application) stravaAuthorize(redirect_uri string, state string) (string, error) {
+
+	creds, err := app.Creds.ReadStravaClientCreds()
+	if err != nil {
+		app.ErrorLog.Printf("[ReadStravaClientCreds] Unable to read Strava client credentials %s", err)
+		return "", err
+	}
+
+	q := url.Values{
+		"client_id":       {strconv.Itoa(creds.Client_ID)},
+		"redirect_uri":    {redirect_uri},
+		"response_type":   {"code"},
+		"approval_prompt": {"force"},
+		"scope":           {stravaScope},
+		"state":           {state},
+	}
+
+	return "https://www.strava.com/oauth/authorize?" + q.Encode(), nil
+}
+
+// Exchange the Strava authorization code and write the user creds file
+func (app *Application) stravaExchange(code string, redirect_uri string) error {
+
+	creds, err := app.Creds.ExchangeStravaCode(app.Ctx, code)
+	if err != nil {
+		app.ErrorLog.Printf("[ExchangeStravaCode] %s", err)
+		return err
+	}
+
+	err = app.Creds.WriteStravaUserCreds(creds)
+	if err != nil {
+		app.ErrorLog.Printf("[WriteStravaUserCreds] %s", err)
+		return err
+	}
+
+	app.InfoLog.Printf("[Auth] Saved Strava user credentials, access token expires %s \n", time.Unix(int64(creds.Expires_At), 0).Format("2006-01-02 15:04"))
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Build the Slack OAuth v2 install URL, using the client ID of the api credentials file
+func (app *Application) slackAuthorize(redirect_uri string, state string) (string, error) {
+
+	creds, err := app.Creds.ReadSlackClientCreds()
+	if err != nil {
+		app.ErrorLog.Printf("[ReadSlackClientCreds] Unable to read Slack client credentials %s", err)
+		return "", err
+	}
+
+	q := url.Values{
+		"client_id":    {creds.Client_ID},
+		"redirect_uri": {redirect_uri},
+		"scope":        {strings.Join(slackScopes, ",")},
+		"state":        {state},
+	}
+
+	return "https://slack.com/oauth/v2/authorize?" + q.Encode(), nil
+}
+
+// Exchange the Slack authorization code for the bot token and write the bot creds file. Settings of an existing
+// bot creds file that are not part of the install, i.e. signing secret and admin token, are kept.
+func (app *Application) slackExchange(code string, redirect_uri string) error {
+
+	client, err := app.Creds.ReadSlackClientCreds()
+	if err != nil {
+		app.ErrorLog.Printf("[ReadSlackClientCreds] Unable to read Slack client credentials %s", err)
+		return err
+	}
+
+	resp, err := app.SlackMemberAPI.OAuthAccess(app.Ctx, client.Client_ID, client.Client_Secret, code, redirect_uri)
+	if err != nil {
+		app.ErrorLog.Printf("[OAuthAccess] %s", err)
+		return err
+	}
+
+	creds, err := app.Creds.ReadSlackBotCreds()
+	if err != nil {
+		creds = client
+	}
+	if creds.Signing_Secret == "" {
+		creds.Signing_Secret = client.Signing_Secret
+	}
+	creds.App_ID = resp.App_ID
+	creds.Client_ID = client.Client_ID
+	creds.Client_Secret = client.Client_Secret
+	creds.Access_Token = resp.Access_Token
+	creds.Refresh_Token = resp.Refresh_Token
+	creds.Expires_At = 0
+	if resp.Refresh_Token != "" {
+		creds.Expires_At = int(time.Now().Unix()) + resp.Expires_In
+	}
+
+	err = app.Creds.WriteSlackBotCreds(creds)
+	if err != nil {
+		app.ErrorLog.Printf("[WriteSlackBotCreds] %s", err)
+		return err
+	}
+
+	app.InfoLog.Printf("[Auth] Saved Slack bot credentials for workspace %s (%s), scopes: %s \n", resp.Team.Name, resp.Team.ID, resp.Scope)
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"svtc-sync/pkg/models"
+)
+
+// Slack escapes user mentions as <@U123|name> and email addresses as <mailto:jane@x.com|jane@x.com>, if escaping
+// is enabled for the slash command. Otherwise a mention is sent as plain @name.
+var (
+	rxMention = regexp.MustCompile(`^<@([A-Z0-9]+)(\|[^>]*)?>$`)
+	rxMailto  = regexp.MustCompile(`^<mailto:([^|>]+)(\|[^>]*)?>$`)
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Handler for the /member Slack slash command, used by workspace admins to look up the membership status of a
+// Slack user (/member @someone) or an email address (/member jane@x.com). The command is acknowledged right
+// away, as Slack times out after 3 seconds, and the lookup responds via the response_url of the command with an
+// ephemeral message, i.e. only visible to the admin, that lists matching member records and why they matched.
+func (app *Application) slackCommand(w http.ResponseWriter, r *http.Request) {
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	responseURL := r.PostForm.Get("response_url")
+	if r.PostForm.Get("command") != "/member" || responseURL == "" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	requester, arg := r.PostForm.Get("user_id"), strings.TrimSpace(r.PostForm.Get("text"))
+
+	go func() {
+		text, err := app.lookupMember(requester, arg)
+		if err != nil {
+			app.ErrorLog.Printf("[slackCommand] %s", err)
+			text = "Lookup failed: " + err.Error()
+		}
+
+		err = app.SlackMemberAPI.Respond(app.Ctx, responseURL, text)
+		if err != nil {
+			app.ErrorLog.Printf("[slackCommand] %s", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusOK)
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Look up the membership status of a Slack user mention or an email address on behalf of the requesting Slack
+// user, who is required to be a workspace admin or owner. Returns the response text.
+func (app *Application) lookupMember(requester string, arg string) (string, error) {
+
+	admin, err := app.SlackMemberAPI.Info(app.Ctx, requester)
+	if err != nil {
+		return "", err
+	}
+	if !admin.Is_Admin && !admin.Is_Owner {
+		app.InfoLog.Printf("[lookupMember] Denied lookup by non-admin user %s", requester)
+		return "Sorry, /member is only available to workspace admins.", nil
+	}
+
+	// Populate a new search member struct with query criteria, as for the slack check but unfiltered. For an
+	// email address, names are set to a value that never matches (as email is for Strava).
+	ms := models.MemberSVTC{Expired: "1963-11-04"}
+	var label string
+
+	if sm := rxMention.FindStringSubmatch(arg); sm != nil || (strings.HasPrefix(arg, "@") && strings.Count(arg, "@") == 1) {
+
+		var user *models.Member
+		if sm != nil {
+			user, err = app.SlackMemberAPI.Info(app.Ctx, sm[1])
+		} else {
+			user, err = app.findSlackUser(strings.TrimPrefix(arg, "@"))
+		}
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			return fmt.Sprintf("%s\nNo single Slack user of that name, use the user's email instead", arg), nil
+		}
+		ms.FirstName = strings.ToLower(user.Profile.FirstName)
+		ms.LastName = strings.ToLower(user.Profile.LastName)
+		ms.Email = strings.ToLower(user.Profile.Email)
+		label = fmt.Sprintf("<@%s> %s %s (%s)", user.ID, user.Profile.FirstName, user.Profile.LastName, user.Profile.Email)
+
+	} else {
+
+		if sm := rxMailto.FindStringSubmatch(arg); sm != nil {
+			arg = sm[1]
+		}
+		if !strings.Contains(arg, "@") {
+			return "Usage: /member @someone or /member jane@x.com", nil
+		}
+		ms.FirstName = string('_')
+		ms.LastName = string('_')
+		ms.Email = strings.ToLower(arg)
+		label = arg
+
+	}
+
+	ml, err := app.MemberSQL.ListMatch("slack", &ms)
+	if err != nil {
+		return "", err
+	}
+
+	ma, al, err := app.MemberSQL.ListAliasMatch(&ms)
+	if err != nil {
+		return "", err
+	}
+
+	app.InfoLog.Printf("[lookupMember] %s looked up %s: %d matches", requester, label, len(ml)+len(ma))
+
+	if len(ml)+len(ma) == 0 {
+		return fmt.Sprintf("%s\nNot Found", label), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", label)
+
+	for _, m := range ml {
+		fmt.Fprintf(&b, "• [%s] %s %s (%s) - *%s* [%s]\n    %s\n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired, explain(&ms, m.FirstName, m.LastName, m.Email))
+	}
+
+	for i, m := range ma {
+		a := al[i]
+		fmt.Fprintf(&b, "• [%s] %s %s (%s) - *%s* [%s]\n    %s via alias %s %s (%s)\n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired, explain(&ms, a.FirstName, a.LastName, a.Email), a.FirstName, a.LastName, a.Email)
+	}
+
+	return b.String(), nil
+
+}
+
+// Find the Slack user of a plain @name mention, by user name, display name or full name (case insensitive).
+// Returns nil if no user, or more than one user has that name.
+func (app *Application) findSlackUser(name string) (*models.Member, error) {
+
+	ul, err := app.SlackMemberAPI.List(app.Ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.Member
+	for i, u := range ul {
+		if u.Deleted {
+			continue
+		}
+		if strings.EqualFold(u.Name, name) || strings.EqualFold(u.Profile.Display, name) || strings.EqualFold(u.Profile.RealName, name) {
+			if found != nil {
+				return nil, nil
+			}
+			found = &ul[i]
+		}
+	}
+
+	return found, nil
+}
+
+// Explain why a record matched the search criteria, i.e. by name, email or both
+func explain(ms *models.MemberSVTC, first, last, email string) string {
+
+	byName := strings.ToLower(first) == ms.FirstName && strings.ToLower(last) == ms.LastName
+	byEmail := strings.ToLower(email) == ms.Email
+
+	switch {
+	case byName && byEmail:
+		return "matched by name and email"
+	case byName:
+		return "matched by name"
+	case byEmail:
+		return "matched by email"
+	}
+
+	return "matched"
+}
+
+// --------------------------------------------------------------------------------------------
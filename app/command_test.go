@@ -0,0 +1,57 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlackCommand(t *testing.T) {
+
+	app, srv := setupPolicy(t)
+
+	form := url.Values{
+		"command":      {"/member"},
+		"user_id":      {"U4"},
+		"text":         {"<@U1|alice>"},
+		"response_url": {srv.URL + "/response"},
+	}
+	req := httptest.NewRequest("POST", "/slack/commands", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	app.slackCommand(w, req)
+
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("response %d %q, expected an empty 200 acknowledgement", w.Code, w.Body.String())
+	}
+
+	// The lookup responds via the response URL once it completes
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.called("response")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	responses := srv.called("response")
+	if len(responses) != 1 || !strings.Contains(responses[0], "[1001] Alice Warn") {
+		t.Fatalf("responses %q, expected the member record of Alice Warn", responses)
+	}
+}
+
+func TestSlackCommandWithoutResponseURL(t *testing.T) {
+
+	app, _ := setupPolicy(t)
+
+	form := url.Values{"command": {"/member"}, "user_id": {"U4"}, "text": {"alice@example.com"}}
+	req := httptest.NewRequest("POST", "/slack/commands", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	app.slackCommand(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("response %d, expected 400 without a response URL", w.Code)
+	}
+}
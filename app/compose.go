@@ -0,0 +1,281 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Built-in reminder template, used when no template file is specified. The subject is defined as a named
+// template within the same template text.
+const defaultTemplate = `{{define "subject"}}Your SVTC membership{{end}}Hi {{.FirstName}},
+
+{{if eq .Status "Expired"}}we noticed that your SVTC membership expired on {{date .Expired}}.{{else if eq .Status "Trial"}}we hope you are enjoying your SVTC trial membership, which ends on {{date .Expired}}.{{else}}your SVTC membership is due for renewal on {{date .Expired}}.{{end}}
+You are still part of our community on Slack and Strava, and we would love to have you back as a member.
+
+Renewing only takes a few minutes at https://www.svtc.org
+
+See you at the next workout!
+SVTC Membership
+`
+
+// Name recorded for the built-in template, e.g. in outreach records
+const defaultTemplateName = "renewal"
+
+// --------------------------------------------------------------------------------------------
+
+// A rendered message for a single member, ready to be written as .eml file, to a mbox or sent via SMTP
+type message struct {
+	Member  *models.MemberSVTC
+	To      string
+	Subject string
+	Body    string
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Return matches of the specified platform against the reference DB
+func (app *Application) matchPlatform(platform string) ([]*Match, error) {
+
+	switch platform {
+	case "slack":
+		return app.MatchSlackMembers()
+	case "strava":
+		return app.MatchStravaMembers()
+	}
+
+	return nil, fmt.Errorf("unsupported platform: %s", platform)
+}
+
+// Return the member records of a list of matches, excluding recently contacted platform users and members
+// without email. Members are deduplicated by email address (ignoring case), the first record found is kept.
+func recipients(mtl []*Match) []*models.MemberSVTC {
+
+	seen := map[string]bool{}
+	ml := []*models.MemberSVTC{}
+
+	for _, mt := range mtl {
+
+		if mt.Contact != nil {
+			continue
+		}
+
+		for _, m := range mt.Members {
+			email := strings.ToLower(strings.TrimSpace(m.Email))
+			if email == "" || seen[email] {
+				continue
+			}
+			seen[email] = true
+			ml = append(ml, m)
+		}
+
+	}
+
+	return ml
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Parse the template file specified via the template option, or the specified built-in template if not set.
+// Returns the template and its name as recorded in outreach records, i.e. the file name without extension.
+func (app *Application) template(defaultName string, defaultText string) (*template.Template, string, error) {
+
+	funcs := template.FuncMap{
+		// Format a YYYY-MM-DD date string as e.g. "December 31, 2023"
+		"date": func(dstr string) string {
+			t := helpers.GetDate(dstr)
+			if t.IsZero() {
+				return dstr
+			}
+			return t.Format("January 2, 2006")
+		},
+	}
+
+	if app.Config.Tmpl == "" {
+		t, err := template.New(defaultName).Funcs(funcs).Parse(defaultText)
+		if err != nil {
+			return nil, "", fmt.Errorf("parse built-in template failed: %w", err)
+		}
+		return t, defaultName, nil
+	}
+
+	data, err := ioutil.ReadFile(app.Config.Tmpl)
+	if err != nil {
+		return nil, "", fmt.Errorf("template file read failed: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(app.Config.Tmpl), filepath.Ext(app.Config.Tmpl))
+
+	t, err := template.New(name).Funcs(funcs).Parse(string(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("parse template file failed: %w", err)
+	}
+
+	return t, name, nil
+}
+
+// Parse the sender address specified via the from option
+func (app *Application) fromAddress() (*mail.Address, error) {
+
+	if app.Config.From == "" {
+		return nil, fmt.Errorf("no sender specified, use -from")
+	}
+
+	from, err := mail.ParseAddress(app.Config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %s: %w", app.Config.From, err)
+	}
+
+	return from, nil
+}
+
+// Render the template for a single member. The subject is taken from the "subject" template if defined.
+func render(t *template.Template, m *models.MemberSVTC) (*message, error) {
+
+	msg := &message{
+		Member:  m,
+		To:      (&mail.Address{Name: m.FirstName + " " + m.LastName, Address: m.Email}).String(),
+		Subject: "SVTC Membership",
+	}
+
+	var buf bytes.Buffer
+
+	if t.Lookup("subject") != nil {
+		err := t.ExecuteTemplate(&buf, "subject", m)
+		if err != nil {
+			return nil, fmt.Errorf("execute subject template failed: %w", err)
+		}
+		msg.Subject = strings.TrimSpace(buf.String())
+		buf.Reset()
+	}
+
+	err := t.Execute(&buf, m)
+	if err != nil {
+		return nil, fmt.Errorf("execute template failed: %w", err)
+	}
+	msg.Body = buf.String()
+
+	return msg, nil
+}
+
+// Format a message in RFC 5322 format with CRLF line endings, as used for .eml files and SMTP
+func (msg *message) bytes(from string) []byte {
+
+	var buf bytes.Buffer
+
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + msg.To + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Render the template for each member matched on the specified platform with the selected status (Expired or
+// Trial) and write the results as individual .eml files to a directory, or as a single mbox file, dependent on
+// configuration options.
+func (app *Application) Compose(platform string) error {
+
+	if app.Config.Output != "EXP" && app.Config.Output != "TRI" {
+		return fmt.Errorf("compose requires an output filter of EXP or TRI")
+	}
+
+	if app.Config.Eml == "" && app.Config.Mbox == "" {
+		return fmt.Errorf("no output specified, use -eml or -mbox")
+	}
+
+	from, err := app.fromAddress()
+	if err != nil {
+		return err
+	}
+
+	t, name, err := app.template(defaultTemplateName, defaultTemplate)
+	if err != nil {
+		app.ErrorLog.Printf("[template] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[Compose] Using template %s \n", name)
+
+	mtl, err := app.matchPlatform(platform)
+	if err != nil {
+		return err
+	}
+
+	ml := recipients(mtl)
+	app.InfoLog.Printf("[Compose] Rendering messages for %d unique member email addresses \n", len(ml))
+
+	var mbox bytes.Buffer
+
+	for _, m := range ml {
+
+		msg, err := render(t, m)
+		if err != nil {
+			app.ErrorLog.Printf("[render] %s: %s", m.Num, err)
+			return err
+		}
+
+		data := msg.bytes(from.String())
+
+		if app.Config.Eml != "" {
+
+			err = os.MkdirAll(app.Config.Eml, 0755)
+			if err != nil {
+				return fmt.Errorf("create directory failed: %w", err)
+			}
+
+			file := filepath.Join(app.Config.Eml, m.Num+".eml")
+			err = ioutil.WriteFile(file, data, 0644)
+			if err != nil {
+				return fmt.Errorf("file write failed: %w", err)
+			}
+			app.InfoLog.Printf("[Compose] Wrote %s for %s", file, m.Email)
+
+		}
+
+		if app.Config.Mbox != "" {
+
+			// mbox uses LF line endings, with a From_ line separating messages and body lines starting
+			// with "From " escaped
+			fmt.Fprintf(&mbox, "From %s %s\n", from.Address, time.Now().Format("Mon Jan _2 15:04:05 2006"))
+			for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+				if strings.HasPrefix(line, "From ") {
+					line = ">" + line
+				}
+				mbox.WriteString(line + "\n")
+			}
+			mbox.WriteString("\n")
+
+		}
+
+	}
+
+	if app.Config.Mbox != "" {
+		err = ioutil.WriteFile(app.Config.Mbox, mbox.Bytes(), 0644)
+		if err != nil {
+			return fmt.Errorf("file write failed: %w", err)
+		}
+		app.InfoLog.Printf("[Compose] Wrote %d messages to %s", len(ml), app.Config.Mbox)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"svtc-sync/pkg/models/api"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Validate each credential of the configured store, i.e. that it is present and complete, that tokens have
+// not expired and that a test call to the platform api succeeds. Secrets are never output, and tokens are
+// checked as stored, i.e. neither refreshed nor written to the store. Returns an error if any credential
+// failed validation.
+func (app *Application) CheckCreds() error {
+
+	checks := map[string]func() (string, error){
+		api.CredsAPI:     app.checkAPICreds,
+		api.CredsStrava:  app.checkStravaCreds,
+		api.CredsSlack:   app.checkSlackCreds,
+		api.CredsExpress: app.checkExpressCreds,
+		api.CredsSMTP:    app.checkSMTPCreds,
+	}
+
+	failed := 0
+
+	for _, name := range api.CredNames {
+
+		detail, err := checks[name]()
+
+		switch {
+		case errors.Is(err, api.ErrNoCreds):
+			fmt.Printf("%-20s MISSING \n", name)
+		case err != nil:
+			fmt.Printf("%-20s FAIL    %s \n", name, err)
+			failed++
+		default:
+			fmt.Printf("%-20s OK      %s \n", name, detail)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d credentials failed validation", failed)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (app *Application) checkAPICreds() (string, error) {
+
+	strava, err := app.Creds.ReadStravaClientCreds()
+	if err != nil {
+		return "", err
+	}
+
+	slack, err := app.Creds.ReadSlackClientCreds()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("strava client %s, slack client %s", configured(strava.Client_ID != 0 && strava.Client_Secret != ""), configured(slack.Client_ID != "" && slack.Client_Secret != "")), nil
+}
+
+// Check the Strava user tokens, and read the club with the access token unless it has expired. An expired
+// access token is refreshed on the next use of the Strava api, not by the check.
+func (app *Application) checkStravaCreds() (string, error) {
+
+	creds, err := app.Creds.ReadStravaUserCreds()
+	if err != nil {
+		return "", err
+	}
+	if creds.Refresh_Token == "" {
+		return "", fmt.Errorf("no refresh token, run auth strava")
+	}
+
+	exp := time.Unix(int64(creds.Expires_At), 0)
+	if !exp.After(time.Now()) {
+		return fmt.Sprintf("access token expired %s, refreshed on next use", exp.Format("2006-01-02 15:04")), nil
+	}
+
+	strava := &api.StravaAthleteModel{Client: &api.AuthClient{Client: app.Creds.Client, Tokens: api.StaticTokens(creds.Access_Token)}}
+	club, err := strava.GetClub(app.Ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("access token valid until %s, club %s (%d members)", exp.Format("2006-01-02 15:04"), club.Name, club.MemberCount), nil
+}
+
+// Check the Slack bot token, and admin token if set, via auth.test
+func (app *Application) checkSlackCreds() (string, error) {
+
+	creds, err := app.Creds.ReadSlackBotCreds()
+	if err != nil {
+		return "", err
+	}
+	if creds.Access_Token == "" {
+		return "", fmt.Errorf("no bot token, run auth slack")
+	}
+
+	// A rotated bot token is refreshed on the next use of the Slack api, not by the check
+	if creds.Refresh_Token != "" && creds.Expires_At < int(time.Now().Unix()) {
+		return "bot token expired, refreshed on next use", nil
+	}
+
+	slack := &api.SlackMemberModel{
+		Client:  api.NewSlackClient(app.Creds.Client, api.StaticTokens(creds.Access_Token)),
+		Admin:   api.NewSlackClient(app.Creds.Client, api.StaticTokens(creds.Admin_Token)),
+		BaseURL: app.SlackMemberAPI.BaseURL,
+	}
+
+	bot, err := slack.AuthTest(app.Ctx, false)
+	if err != nil {
+		return "", fmt.Errorf("bot token: %w", err)
+	}
+
+	detail := fmt.Sprintf("bot %s in workspace %s, signing secret %s", bot.User, bot.Team, configured(creds.Signing_Secret != ""))
+
+	if creds.Admin_Token != "" {
+		admin, err := slack.AuthTest(app.Ctx, true)
+		if err != nil {
+			return "", fmt.Errorf("admin token: %w", err)
+		}
+		detail += fmt.Sprintf(", admin token of %s", admin.User)
+	}
+
+	return detail, nil
+}
+
+// Check the ClubExpress access key with a status request, which is refused (403) for an invalid key
+func (app *Application) checkExpressCreds() (string, error) {
+
+	creds, err := app.Creds.ReadExpressCreds()
+	if err != nil {
+		return "", err
+	}
+	if creds.Access_Key == "" {
+		return "", fmt.Errorf("no access key")
+	}
+
+	ret, err := app.ExpressMemberAPI.GetStatus(app.Ctx, 0, "")
+	if err != nil {
+		return "", err
+	}
+
+	return "member status api accepted access key (" + strconv.Itoa(ret) + ")", nil
+}
+
+// Check the SMTP relay settings by connecting and authenticating, without sending a message
+func (app *Application) checkSMTPCreds() (string, error) {
+
+	creds, err := app.Creds.ReadSMTPCreds()
+	if err != nil {
+		return "", err
+	}
+
+	err = app.MailAPI.Check(creds)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("relay %s:%d, authentication %s", creds.Host, creds.Port, configured(creds.Username != "")), nil
+}
+
+func configured(ok bool) string {
+
+	if ok {
+		return "configured"
+	}
+
+	return "not configured"
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Import plaintext credential files from a directory, e.g. an existing ./.secret, into the configured store,
+// e.g. to encrypt them. The plaintext files are left in place to be removed by the user.
+func (app *Application) ImportCreds(dir string) error {
+
+	src := &api.FileStore{Dir: dir}
+
+	for _, name := range api.CredNames {
+
+		data, err := src.Read(name)
+		if errors.Is(err, api.ErrNoCreds) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if app.Config.Preview {
+			fmt.Printf("%s \n", name)
+			continue
+		}
+
+		err = app.Creds.Store.Write(name, data)
+		if err != nil {
+			app.ErrorLog.Printf("[ImportCreds] %s", err)
+			return err
+		}
+		fmt.Printf("%s imported \n", name)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
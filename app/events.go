@@ -0,0 +1,151 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"svtc-sync/pkg/models"
+)
+
+// Period within which Slack retries the delivery of an event, i.e. after 1, 5 and 30 minutes
+const eventRetryPeriod = time.Hour
+
+// IDs of the events received within the retry period, to process each event only once
+var seenEvents = struct {
+	sync.Mutex
+	ids map[string]time.Time
+}{ids: map[string]time.Time{}}
+
+// --------------------------------------------------------------------------------------------
+
+// Handler for the Slack Events API. Responds to the url_verification challenge, and checks users of
+// team_join and user_change events against the reference DB. Events are acknowledged immediately and
+// processed in the background, as Slack expects a response within 3 seconds. Retries of events that were
+// received already (see the X-Slack-Retry-Num header) are acknowledged, but not processed again.
+func (app *Application) slackEvents(w http.ResponseWriter, r *http.Request) {
+
+	req := models.EventRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Type {
+
+	case "url_verification":
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(req.Challenge))
+		return
+
+	case "event_callback":
+
+		if req.Event.Type != "team_join" && req.Event.Type != "user_change" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if seenEvent(req.EventID) {
+			app.InfoLog.Printf("[slackEvents] Ignoring retry %s of event %s", r.Header.Get("X-Slack-Retry-Num"), req.EventID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		user := models.Member{}
+
+		err = json.Unmarshal(req.Event.User, &user)
+		if err != nil {
+			app.ErrorLog.Printf("[slackEvents] unmarshal %s user failed: %s", req.Event.Type, err)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		go app.checkSlackUser(req.Event.Type, user)
+
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+}
+
+// Record the ID of an event, returns true if it was received within the retry period already. Events without
+// an ID are never considered seen.
+func seenEvent(id string) bool {
+
+	if id == "" {
+		return false
+	}
+
+	seenEvents.Lock()
+	defer seenEvents.Unlock()
+
+	now := time.Now()
+	for k, t := range seenEvents.ids {
+		if now.Sub(t) > eventRetryPeriod {
+			delete(seenEvents.ids, k)
+		}
+	}
+
+	if _, ok := seenEvents.ids[id]; ok {
+		return true
+	}
+	seenEvents.ids[id] = now
+
+	return false
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Check a single Slack user from an event against the reference DB with the same logic as CheckSlackMembers
+// and log the result. The result is posted to the channel specified via the post option for all new users,
+// and for changed users that are not matched to an Active or Trial member. Users that have not confirmed their
+// email yet are checked as well, and checked again by the user_change event of the confirmation.
+func (app *Application) checkSlackUser(event string, user models.Member) {
+
+	// Ignore bot or app records and deactivated users
+	if user.Is_Bot || user.ID == "USLACKBOT" || user.Deleted {
+		return
+	}
+
+	mt, err := app.matchSlack(user)
+	if err != nil {
+		app.ErrorLog.Printf("[checkSlackUser] %s: %s", user.ID, err)
+		return
+	}
+
+	result := statusNotFound
+	if len(mt.Members) > 0 {
+		m := mt.Members[0]
+		result = fmt.Sprintf("[%s] %s %s (%s) - %s [%s]", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
+	}
+	if !user.Is_Email_Confirmed {
+		result += " (email not confirmed)"
+	}
+	app.InfoLog.Printf("[checkSlackUser] %s %s %s", event, mt.Label(), result)
+
+	if app.Config.Post == "" {
+		return
+	}
+
+	if event == "user_change" && len(mt.Members) > 0 && (mt.Members[0].Status == models.StatusActive || mt.Members[0].Status == models.StatusTrial) {
+		return
+	}
+
+	text := fmt.Sprintf("*%s* <@%s> %s\n%s", event, mt.ID, mt.Label(), result)
+	if len(mt.Members) > 1 {
+		text += fmt.Sprintf("\n(%d matching member records)", len(mt.Members))
+	}
+
+	err = app.SlackMemberAPI.PostMessage(app.Ctx, app.Config.Post, text)
+	if err != nil {
+		app.ErrorLog.Printf("[PostMessage] %s", err)
+	}
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Expiration policies of a membership
+const (
+	expireCalendar = "calendar" // End of the calendar year, or of the next year once the renewal window opened
+	expireRolling  = "rolling"  // A number of months from the join date, renewed on each anniversary
+	expireFeed     = "feed"     // Expired date of the actives JSON file, calendar year if not present
+)
+
+// Rule to determine the expiration date of a membership
+type expireRule struct {
+	Policy string `json:"policy"` // calendar, rolling or feed
+	Cutoff string `json:"cutoff"` // Start of the renewal window (MM-DD), from which calendar memberships expire at the end of the next year
+	Months int    `json:"months"` // Length of rolling memberships in months
+}
+
+// Expiration policy file, with a default rule and rules by membership type, e.g.
+//
+//	{
+//	  "default": {"policy": "calendar", "cutoff": "12-01"},
+//	  "types": {
+//	    "Trial": {"policy": "rolling", "months": 1},
+//	    "Monthly": {"policy": "feed"}
+//	  }
+//	}
+type expirePolicy struct {
+	Default expireRule            `json:"default"`
+	Types   map[string]expireRule `json:"types"`
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Load the expiration policy from the file of the expiry option. Without it, all memberships expire at the
+// end of the calendar year.
+func (app *Application) expirePolicy() (*expirePolicy, error) {
+
+	p := &expirePolicy{
+		Default: expireRule{Policy: expireCalendar},
+		Types:   map[string]expireRule{},
+	}
+
+	if app.Config.Expiry == "" {
+		return p, nil
+	}
+
+	data, err := ioutil.ReadFile(app.Config.Expiry)
+	if err != nil {
+		return nil, fmt.Errorf("expiration policy file read failed: %w", err)
+	}
+
+	err = json.Unmarshal(data, p)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal of expiration policy %s failed: %w", app.Config.Expiry, err)
+	}
+
+	err = p.Default.validate()
+	if err != nil {
+		return nil, fmt.Errorf("default expiration policy: %w", err)
+	}
+	for t, r := range p.Types {
+		err = r.validate()
+		if err != nil {
+			return nil, fmt.Errorf("expiration policy of membership type %s: %w", t, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Returns the rule of a membership type, or the default rule if the type has none
+func (p *expirePolicy) rule(membershipType string) expireRule {
+
+	if r, ok := p.Types[membershipType]; ok {
+		return r
+	}
+
+	return p.Default
+}
+
+// Returns the expiration date (YYYY-MM-DD) of a member's membership as of the given date, per the rule of its
+// membership type
+func (p *expirePolicy) expires(m *models.MemberSVTC, now time.Time) string {
+
+	return p.rule(m.Type).expires(m, now)
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (r expireRule) validate() error {
+
+	switch r.Policy {
+
+	case expireCalendar, expireFeed:
+		if r.Cutoff != "" && r.cutoff(2000).IsZero() {
+			return fmt.Errorf("invalid cutoff %q, expected MM-DD", r.Cutoff)
+		}
+
+	case expireRolling:
+		if r.Months <= 0 {
+			return fmt.Errorf("invalid number of months %d", r.Months)
+		}
+
+	default:
+		return fmt.Errorf("unknown policy %q, expected calendar, rolling or feed", r.Policy)
+	}
+
+	return nil
+}
+
+// Start of the renewal window in the given year, zero time if there is none
+func (r expireRule) cutoff(year int) time.Time {
+
+	if r.Cutoff == "" {
+		return time.Time{}
+	}
+
+	return helpers.GetDate(fmt.Sprintf("%04d-%s", year, r.Cutoff))
+}
+
+func (r expireRule) expires(m *models.MemberSVTC, now time.Time) string {
+
+	const layout = "2006-01-02"
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch r.Policy {
+
+	case expireRolling:
+
+		// Next anniversary of the join date (or today if unknown) that is still ahead, the day before it
+		joined := helpers.GetDate(m.Joined)
+		if joined.IsZero() {
+			joined = today
+		}
+		t := joined.AddDate(0, r.Months, -1)
+		for k := 2; t.Before(today); k++ {
+			t = joined.AddDate(0, k*r.Months, -1)
+		}
+		return t.Format(layout)
+
+	case expireFeed:
+
+		if !helpers.GetDate(m.Expired).IsZero() {
+			return m.Expired
+		}
+	}
+
+	// Calendar year, memberships renewed in the renewal window run until the end of the next year
+	year := today.Year()
+	if c := r.cutoff(year); !c.IsZero() && !today.Before(c) {
+		year++
+	}
+
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).Format(layout)
+}
+
+// Description of the rule for log output
+func (r expireRule) String() string {
+
+	switch r.Policy {
+	case expireRolling:
+		return fmt.Sprintf("rolling %d months from join date", r.Months)
+	case expireFeed:
+		return "expired date of the JSON file"
+	}
+
+	if r.Cutoff != "" {
+		return "calendar year, next year from " + r.Cutoff
+	}
+
+	return "calendar year"
+}
+
+// --------------------------------------------------------------------------------------------
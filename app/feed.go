@@ -0,0 +1,338 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Return the version of the actives JSON file to sync. With the file option this is an archived (or any local)
+// file. Otherwise the file is fetched from ClubExpress, conditional on a change since the last synced version;
+// returns nil if it has not changed.
+func (app *Application) activesFeed() (*models.Feed, error) {
+
+	if app.Config.File != "" {
+		return app.loadFeed(app.Config.File)
+	}
+
+	last, err := app.FeedSQL.Latest()
+	if err != nil {
+		app.ErrorLog.Printf("[Feed SQL] %s", err)
+		return nil, err
+	}
+
+	etag, lastModified := "", ""
+	if last != nil {
+		etag, lastModified = last.ETag, last.LastModified
+	}
+
+	f, err := app.ExpressMemberAPI.FetchActives(app.Ctx, etag, lastModified)
+	if err != nil {
+		app.ErrorLog.Printf("[FetchActives] %s", err)
+		return nil, err
+	}
+
+	// Not modified without a synced version, e.g. a proxy that answers a request without conditional headers
+	// with a 304
+	if f == nil && last == nil {
+		err = fmt.Errorf("JSON File reported as not modified, but no version was synced before")
+		app.ErrorLog.Printf("[FetchActives] %s", err)
+		return nil, err
+	}
+
+	// Not modified, or modified without a change of the content
+	if last != nil && (f == nil || f.Checksum == last.Checksum) {
+		app.InfoLog.Printf("[ActivesSync] JSON File unchanged since %s, last synced %s \n", last.LastModified, last.Fetched)
+		return nil, nil
+	}
+
+	app.InfoLog.Printf("[ActivesSync] JSON File Date: %s \n", f.LastModified)
+
+	return f, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Load a version of the actives JSON file from a local file, which is decompressed if gzipped. If the file does
+// not exist, the path is looked up as (prefix of) the checksum of an archived version. The checksum is verified
+// against the archive record or a .sha256 file next to the file, if either exists.
+func (app *Application) loadFeed(path string) (*models.Feed, error) {
+
+	var rec *models.Feed
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		rec, err = app.FeedSQL.Get(path)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no file or archived version %s", path)
+		}
+		if err != nil {
+			app.ErrorLog.Printf("[Feed SQL] %s", err)
+			return nil, err
+		}
+		path = rec.File
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file read failed: %w", err)
+	}
+
+	f := &models.Feed{File: path}
+
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip read of %s failed: %w", path, err)
+		}
+		data, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("gzip read of %s failed: %w", path, err)
+		}
+		f.LastModified = zr.Header.Comment
+	}
+
+	sum := sha256.Sum256(data)
+	f.Checksum = hex.EncodeToString(sum[:])
+	f.Data = data
+
+	expected := ""
+	if rec != nil {
+		expected = rec.Checksum
+	} else if side, err := ioutil.ReadFile(path + ".sha256"); err == nil {
+		expected = strings.Fields(string(side) + " ")[0]
+	}
+	if expected != "" && expected != f.Checksum {
+		return nil, fmt.Errorf("checksum mismatch of %s: expected %s, got %s", path, expected, f.Checksum)
+	}
+
+	app.InfoLog.Printf("[ActivesSync] Loaded JSON File %s (sha256 %s) \n", path, f.Checksum)
+
+	return f, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Archive a version of the actives JSON file gzipped in the archive directory, along with a .sha256 file in
+// the format of sha256sum. Sets the path of the archive file.
+func (app *Application) archiveFeed(f *models.Feed) error {
+
+	err := os.MkdirAll(app.Config.Archive, 0755)
+	if err != nil {
+		return fmt.Errorf("create archive directory failed: %w", err)
+	}
+
+	name := fmt.Sprintf("actives-%s-%s.json.gz", time.Now().Format("20060102-150405"), f.Checksum[:12])
+	path := filepath.Join(app.Config.Archive, name)
+
+	// The Last-Modified date of the file is kept in the gzip header
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = "actives.json"
+	zw.Comment = f.LastModified
+	zw.ModTime = time.Now()
+
+	_, err = zw.Write(f.Data)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("gzip of actives JSON failed: %w", err)
+	}
+
+	err = ioutil.WriteFile(path, buf.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+
+	err = ioutil.WriteFile(path+".sha256", []byte(f.Checksum+"  "+name+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+
+	f.File = path
+	app.InfoLog.Printf("[ActivesSync] Archived JSON File as %s \n", path)
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Record of the actives JSON file rejected by validation, or because it could not be decoded
+type activesReject struct {
+	Num    string // Member number, if any
+	Name   string // First and last name, or the index of a record that could not be decoded
+	Reason string
+}
+
+// Validate the records of the actives JSON file, so that blank or reshaped records are not written to the DB.
+// Member number, names and email are required, and dates must be of the form YYYY-MM-DD. Returns the valid
+// records and the rejected records with the reason.
+func validateActives(ml []*models.MemberSVTC) ([]*models.MemberSVTC, []*activesReject) {
+
+	valid := []*models.MemberSVTC{}
+	rejected := []*activesReject{}
+	seen := map[string]bool{}
+
+	for _, m := range ml {
+
+		m.Num = strings.TrimSpace(m.Num)
+		m.FirstName = strings.TrimSpace(m.FirstName)
+		m.LastName = strings.TrimSpace(m.LastName)
+		m.Email = strings.TrimSpace(m.Email)
+
+		reason := ""
+		num, err := strconv.Atoi(m.Num)
+
+		switch {
+		case m.Num == "":
+			reason = "missing memberNumber"
+		case err != nil || num <= 0:
+			reason = "invalid memberNumber " + m.Num
+		case seen[m.Num]:
+			reason = "duplicate memberNumber " + m.Num
+		case m.FirstName == "" || m.LastName == "":
+			reason = "missing firstName or lastName"
+		case m.Email == "":
+			reason = "missing email"
+		case !validEmail(m.Email):
+			reason = "invalid email " + m.Email
+		case m.Joined != "" && helpers.GetDate(m.Joined).IsZero():
+			reason = "invalid joined date " + m.Joined
+		case m.Expired != "" && helpers.GetDate(m.Expired).IsZero():
+			reason = "invalid expired date " + m.Expired
+		}
+
+		if reason != "" {
+			rejected = append(rejected, &activesReject{Num: m.Num, Name: strings.TrimSpace(m.FirstName + " " + m.LastName), Reason: reason})
+			continue
+		}
+
+		seen[m.Num] = true
+		valid = append(valid, m)
+	}
+
+	return valid, rejected
+}
+
+// An email address without display name, e.g. "name@example.com"
+func validEmail(email string) bool {
+
+	a, err := mail.ParseAddress(email)
+
+	return err == nil && a.Address == email
+}
+
+// Check the number of valid records of the actives JSON file against the last synced version. The sync is
+// refused if there are none, or if the count dropped by more than the drop option (in percent).
+func (app *Application) checkActivesCount(count int) error {
+
+	if count == 0 {
+		return fmt.Errorf("no valid member records in actives JSON file, refusing to sync")
+	}
+
+	last, err := app.FeedSQL.Latest()
+	if err != nil {
+		app.ErrorLog.Printf("[Feed SQL] %s", err)
+		return err
+	}
+	if last == nil || last.Count == 0 {
+		return nil
+	}
+
+	drop := 100 * (last.Count - count) / last.Count
+	if drop > app.Config.Drop {
+		return fmt.Errorf("actives count dropped by %d%% from %d to %d since %s (maximum %d%%), refusing to sync", drop, last.Count, count, last.Fetched, app.Config.Drop)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Records of the last synced version of the actives JSON file by member number, read from its archived file.
+// Returns nil if there is no synced version, or if its archived file is not available.
+func (app *Application) lastActives() (map[string]*models.MemberSVTC, error) {
+
+	last, err := app.FeedSQL.Latest()
+	if err != nil {
+		app.ErrorLog.Printf("[Feed SQL] %s", err)
+		return nil, err
+	}
+	if last == nil || last.File == "" {
+		return nil, nil
+	}
+
+	f, err := app.loadFeed(last.File)
+	if err != nil {
+		app.InfoLog.Printf("[ActivesSync] Last synced JSON File not available, no changed records: %s", err)
+		return nil, nil
+	}
+
+	ml, _, err := app.ExpressMemberAPI.ParseActives(f.Data)
+	if err != nil {
+		app.InfoLog.Printf("[ActivesSync] Last synced JSON File not parsed, no changed records: %s", err)
+		return nil, nil
+	}
+	ml, _ = validateActives(ml)
+
+	records := map[string]*models.MemberSVTC{}
+	for _, m := range ml {
+		records[m.Num] = m
+	}
+
+	return records, nil
+}
+
+// Whether the JSON file shows that an active member renewed: an expired date of the file under the feed policy,
+// or a record that is new, or whose joined or expired date changed since the last synced version. Other changes,
+// e.g. of the address, are no evidence of a renewal. Without it, the expired date of an active member is not
+// extended.
+func renewed(m *models.MemberSVTC, rule expireRule, last map[string]*models.MemberSVTC) bool {
+
+	if rule.Policy == expireFeed && !helpers.GetDate(m.Expired).IsZero() {
+		return true
+	}
+	if last == nil {
+		return false
+	}
+
+	prev, ok := last[m.Num]
+
+	return !ok || prev.Joined != m.Joined || prev.Expired != m.Expired
+}
+
+// --------------------------------------------------------------------------------------------
+
+// List the archived versions of the actives JSON file, most recent first
+func (app *Application) ListFeeds() error {
+
+	fl, err := app.FeedSQL.List(0)
+	if err != nil {
+		app.ErrorLog.Printf("[Feed SQL] %s", err)
+		return err
+	}
+
+	for _, f := range fl {
+		fmt.Printf("%s %s %5d members [%s] %s \n", f.Fetched, f.Checksum[:12], f.Count, f.LastModified, f.File)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
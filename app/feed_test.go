@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"svtc-sync/pkg/models"
+	"svtc-sync/pkg/models/api"
+	"svtc-sync/pkg/models/sqlite"
+)
+
+// Transport of an http client, that answers all requests with the function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// --------------------------------------------------------------------------------------------
+
+func TestRenewed(t *testing.T) {
+
+	calendar := expireRule{Policy: expireCalendar}
+	feed := expireRule{Policy: expireFeed}
+
+	member := func(expired string, email string) *models.MemberSVTC {
+		return &models.MemberSVTC{Num: "1001", FirstName: "Dave", LastName: "Scott", Email: email, Joined: "2015-01-01", Expired: expired}
+	}
+	last := map[string]*models.MemberSVTC{"1001": member("", "dave@example.com")}
+
+	for _, tc := range []struct {
+		name string
+		m    *models.MemberSVTC
+		rule expireRule
+		last map[string]*models.MemberSVTC
+		want bool
+	}{
+		{"unchanged record", member("", "dave@example.com"), calendar, last, false},
+		{"changed email", member("", "dave@example.net"), calendar, last, false},
+		{"changed expired date", member("2022-12-31", "dave@example.com"), calendar, last, true},
+		{"changed joined date", &models.MemberSVTC{Num: "1001", FirstName: "Dave", LastName: "Scott", Email: "dave@example.com", Joined: "2021-01-01"}, calendar, last, true},
+		{"new record", member("", "dave@example.com"), calendar, map[string]*models.MemberSVTC{}, true},
+		{"no synced version", member("", "dave@example.com"), calendar, nil, false},
+		{"feed expired date", member("2022-06-30", "dave@example.com"), feed, nil, true},
+		{"feed without expired date", member("", "dave@example.com"), feed, last, false},
+	} {
+		if got := renewed(tc.m, tc.rule, tc.last); got != tc.want {
+			t.Errorf("%s: renewed %v, expected %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestActivesFeedNotModified(t *testing.T) {
+
+	db, _ := newTestDB(t)
+
+	// A proxy that answers every request with a 304, whether conditional or not
+	client := &api.Client{HTTP: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotModified, Status: "304 Not Modified", Body: ioutil.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+	})}}
+
+	app := &Application{
+		ErrorLog:         log.New(ioutil.Discard, "", 0),
+		InfoLog:          log.New(ioutil.Discard, "", 0),
+		Ctx:              context.Background(),
+		Config:           &Configuration{},
+		FeedSQL:          &sqlite.FeedModel{DB: db},
+		ExpressMemberAPI: &api.ExpressMemberModel{Client: &api.AuthClient{Client: client}},
+	}
+	err := app.FeedSQL.Init()
+	if err != nil {
+		t.Fatalf("init db failed: %s", err)
+	}
+
+	_, err = app.activesFeed()
+	if err == nil {
+		t.Fatalf("activesFeed without synced version: expected error")
+	}
+
+	err = app.FeedSQL.Insert(&models.Feed{LastModified: "Wed, 01 Sep 2021 11:00:00 GMT", ETag: `"abc"`, Checksum: "abc", Count: 1})
+	if err != nil {
+		t.Fatalf("insert feed failed: %s", err)
+	}
+
+	f, err := app.activesFeed()
+	if err != nil || f != nil {
+		t.Errorf("activesFeed with synced version: %v %v, expected no new version", f, err)
+	}
+}
@@ -0,0 +1,333 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Windows of upcoming expirations in days from today, each starting after the previous one
+var forecastWindows = []int{30, 60, 90}
+
+// Cohorts of members by tenure, i.e. full years of membership, at the time their membership is due for renewal
+var forecastCohorts = []struct {
+	Name  string
+	Years int // Minimum tenure in years
+}{
+	{"3+ years", 3},
+	{"1-2 years", 1},
+	{"first year", 0},
+}
+
+// Cohort of members on a trial membership, whose renewal is a conversion
+const trialCohort = "trial"
+
+// Weight of the cohort rate in the likelihood of a member, in years of the member's own history, i.e. a member
+// with that many renewals due is estimated half by the cohort rate and half by their own renewals
+const historyWeight = 3.0
+
+// Renewal history of a cohort
+type cohortRate struct {
+	Members int
+	Renewed int
+}
+
+// Rate of the cohort as a fraction, and false if the cohort has no history
+func (c *cohortRate) rate() (float64, bool) {
+
+	if c == nil || c.Members == 0 {
+		return 0, false
+	}
+
+	return float64(c.Renewed) / float64(c.Members), true
+}
+
+// Member expiring within the forecast windows, with the estimated likelihood of renewal
+type forecastMember struct {
+	Member     *models.MemberSVTC
+	Cohort     string
+	Years      int     // Renewals due in the member's own history, i.e. full years since joining
+	Lapses     int     // Renewals missed, i.e. lapses followed by a rejoin
+	Likelihood float64 // Fraction, negative if unknown
+}
+
+// --------------------------------------------------------------------------------------------
+
+// List the Active and Trial members expiring in the next 30, 60 and 90 days, with the likelihood of their
+// renewal based on their own history and the historic renewal rate of their cohort, i.e. their tenure or trial.
+// The cohort rate is weighed against the member's own renewals, so that a long record of renewals raises the
+// likelihood, and lapses followed by a rejoin lower it. With the email option, the members are output in RFC 5322
+// format instead, e.g. for a pre-expiry campaign. Members contacted within the recent window are suppressed.
+func (app *Application) Forecast() error {
+
+	now := time.Now().Local()
+	today := now.Format("2006-01-02")
+
+	ml, err := app.MemberSQL.ListMembers()
+	if err != nil {
+		app.ErrorLog.Printf("[ListMembers SQL] %s", err)
+		return err
+	}
+
+	st, err := app.stats(now)
+	if err != nil {
+		return err
+	}
+
+	contacts, err := app.recentOutreach()
+	if err != nil {
+		app.ErrorLog.Printf("[recentOutreach] %s", err)
+		return err
+	}
+
+	lapses, err := app.memberLapses()
+	if err != nil {
+		return err
+	}
+
+	rates := cohortRates(ml, now.Year())
+	rates[trialCohort] = &cohortRate{Members: st.Trials.Ended, Renewed: st.Trials.Converted}
+
+	overall := &cohortRate{}
+	for _, c := range forecastCohorts {
+		overall.Members += rates[c.Name].Members
+		overall.Renewed += rates[c.Name].Renewed
+	}
+
+	// Members expiring within the windows, by window
+	windows := make([][]*forecastMember, len(forecastWindows))
+	for _, m := range ml {
+
+		if m.Status != models.StatusActive && m.Status != models.StatusTrial {
+			continue
+		}
+		if _, ok := contacts["num:"+m.Num]; ok {
+			continue
+		}
+
+		exp := helpers.GetDate(m.Expired)
+		if exp.IsZero() || m.Expired < today {
+			continue
+		}
+		days := int(exp.Sub(helpers.GetDate(today)).Hours() / 24)
+
+		for i, w := range forecastWindows {
+			if days > w {
+				continue
+			}
+
+			fm := &forecastMember{Member: m, Cohort: cohortOf(m, exp), Years: tenureYears(m, exp), Lapses: lapses[m.Num], Likelihood: -1}
+			if r, ok := rates[fm.Cohort].rate(); ok {
+				fm.Likelihood = likelihood(r, fm.Years, fm.Lapses)
+			} else if r, ok := overall.rate(); ok {
+				fm.Likelihood = likelihood(r, fm.Years, fm.Lapses)
+			}
+
+			windows[i] = append(windows[i], fm)
+			break
+		}
+	}
+
+	if app.Config.Email {
+		for _, fl := range windows {
+			for _, fm := range fl {
+				if fm.Member.Email != "" {
+					fmt.Printf("%s %s <%s>,\n", fm.Member.FirstName, fm.Member.LastName, fm.Member.Email)
+				}
+			}
+		}
+		return nil
+	}
+
+	fmt.Printf("Renewal forecast as of %s\n\n", today)
+
+	cl := []string{}
+	for _, c := range forecastCohorts {
+		cl = append(cl, c.Name)
+	}
+	cl = append(cl, trialCohort)
+
+	fmt.Printf("Likelihoods weigh the cohort rate against the member's own renewals and lapses\n\n")
+	fmt.Printf("Cohort renewal rates:\n")
+	for _, name := range cl {
+		r, ok := rates[name].rate()
+		if !ok {
+			fmt.Printf("  %-12s no history\n", name)
+			continue
+		}
+		fmt.Printf("  %-12s %5.1f%% (%d of %d)\n", name, 100*r, rates[name].Renewed, rates[name].Members)
+	}
+
+	from := 0
+	for i, fl := range windows {
+
+		sort.Slice(fl, func(a, b int) bool { return fl[a].Member.Expired < fl[b].Member.Expired })
+
+		expected := 0.0
+		for _, fm := range fl {
+			if fm.Likelihood >= 0 {
+				expected += fm.Likelihood
+			}
+		}
+
+		fmt.Printf("\nExpiring in %d-%d days: %d members, %.1f expected to renew\n", from, forecastWindows[i], len(fl), expected)
+		for _, fm := range fl {
+			m := fm.Member
+			likelihood := "n/a"
+			if fm.Likelihood >= 0 {
+				likelihood = fmt.Sprintf("%.0f%%", 100*fm.Likelihood)
+			}
+			if fm.Lapses > 0 {
+				likelihood += fmt.Sprintf(" (%d lapses)", fm.Lapses)
+			}
+			fmt.Printf("\t[%s] %s %s (%s) - %s [%s] %s %s \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired, fm.Cohort, likelihood)
+		}
+
+		from = forecastWindows[i] + 1
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Renewal history by tenure cohort over the recent years, i.e. of the members at the start of a year, those
+// that were still members at the start of the next year. Cohorts are determined by the tenure at the start of
+// the year.
+func cohortRates(ml []*models.MemberSVTC, year int) map[string]*cohortRate {
+
+	rates := map[string]*cohortRate{}
+	for _, c := range forecastCohorts {
+		rates[c.Name] = &cohortRate{}
+	}
+
+	for y := year - statsYears; y < year; y++ {
+		start, next := fmt.Sprintf("%d-01-01", y), fmt.Sprintf("%d-01-01", y+1)
+		for _, m := range ml {
+			if !memberOn(m, start) {
+				continue
+			}
+			c := rates[tenureCohort(m, helpers.GetDate(start))]
+			c.Members++
+			if memberOn(m, next) {
+				c.Renewed++
+			}
+		}
+	}
+
+	return rates
+}
+
+// Cohort of a member by tenure on the given date, or the trial cohort
+func cohortOf(m *models.MemberSVTC, date time.Time) string {
+
+	if m.Status == models.StatusTrial {
+		return trialCohort
+	}
+
+	return tenureCohort(m, date)
+}
+
+// Cohort of a member by tenure on the given date, i.e. the full years since joining
+func tenureCohort(m *models.MemberSVTC, date time.Time) string {
+
+	years := tenureYears(m, date)
+
+	for _, c := range forecastCohorts {
+		if years >= c.Years {
+			return c.Name
+		}
+	}
+
+	return forecastCohorts[len(forecastCohorts)-1].Name
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Full years since a member joined on the given date, 0 if the joined date is unknown
+func tenureYears(m *models.MemberSVTC, date time.Time) int {
+
+	joined := helpers.GetDate(m.Joined)
+	years := 0
+	for !joined.IsZero() && !joined.AddDate(years+1, 0, 0).After(date) {
+		years++
+	}
+
+	return years
+}
+
+// Likelihood of a member's renewal, i.e. the cohort rate weighed against the renewals of the member's own
+// history. Each lapse counts as a renewal missed; if a member rejoined with a new joined date, the lapses are
+// renewals due in addition to the years since joining.
+func likelihood(rate float64, years int, lapses int) float64 {
+
+	due := years
+	if lapses > due {
+		due = lapses
+	}
+
+	return (rate*historyWeight + float64(due-lapses)) / (historyWeight + float64(due))
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Number of lapses followed by a rejoin by member number, i.e. runs in which the member was matched as Expired or
+// Dropped and a later run as Active or Trial, or merges of a re-registered record into the member's record. As a
+// merge moves the run history of the re-registered record, both may show the same lapse; the larger count is used.
+func (app *Application) memberLapses() (map[string]int, error) {
+
+	statuses, err := app.RunSQL.Statuses()
+	if err != nil {
+		app.ErrorLog.Printf("[Run SQL] %s", err)
+		return nil, err
+	}
+
+	lapses := map[string]int{}
+	for num, sl := range statuses {
+		if n := countLapses(sl); n > 0 {
+			lapses[num] = n
+		}
+	}
+
+	mml, err := app.MergeSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[Merge SQL] %s", err)
+		return nil, err
+	}
+	merges := map[string]int{}
+	for _, mm := range mml {
+		merges[mm.KeepNum]++
+	}
+	for num, n := range merges {
+		if n > lapses[num] {
+			lapses[num] = n
+		}
+	}
+
+	return lapses, nil
+}
+
+// Number of lapses in a sequence of statuses, i.e. of Expired or Dropped followed by Active or Trial
+func countLapses(sl []string) int {
+
+	n := 0
+	lapsed := false
+	for _, s := range sl {
+		switch models.Status(s) {
+		case models.StatusExpired, models.StatusDropped:
+			lapsed = true
+		case models.StatusActive, models.StatusTrial:
+			if lapsed {
+				n++
+			}
+			lapsed = false
+		}
+	}
+
+	return n
+}
+
+// --------------------------------------------------------------------------------------------
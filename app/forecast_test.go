@@ -0,0 +1,48 @@
+package app
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCountLapses(t *testing.T) {
+
+	for _, tc := range []struct {
+		statuses []string
+		want     int
+	}{
+		{[]string{"Active", "Active"}, 0},
+		{[]string{"Trial", "Active", "Expired"}, 0},
+		{[]string{"Active", "Expired", "Active"}, 1},
+		{[]string{"Active", "Expired", "Dropped", "Trial", "Active", "Expired", "Active"}, 2},
+		{[]string{"Expired", "Not Found", "Active"}, 1},
+	} {
+		if got := countLapses(tc.statuses); got != tc.want {
+			t.Errorf("countLapses(%v) = %d, expected %d", tc.statuses, got, tc.want)
+		}
+	}
+}
+
+func TestLikelihood(t *testing.T) {
+
+	for _, tc := range []struct {
+		name   string
+		rate   float64
+		years  int
+		lapses int
+		want   float64
+	}{
+		{"no history", 0.8, 0, 0, 0.8},
+		{"renewed every year", 0.8, 3, 0, 0.9},
+		{"one lapse in three years", 0.8, 3, 1, (2.4 + 2) / 6},
+		{"rejoined with new joined date", 0.8, 0, 2, 2.4 / 5},
+	} {
+		if got := likelihood(tc.rate, tc.years, tc.lapses); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: likelihood %.4f, expected %.4f", tc.name, got, tc.want)
+		}
+	}
+
+	if likelihood(0.8, 3, 1) >= likelihood(0.8, 3, 0) {
+		t.Errorf("a lapse does not lower the likelihood")
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Keep a members-only Slack user group (ID starting with S) or channel (ID starting with C or G) in sync with
+// the Slack users matched to Active or Trial members. Users missing from the group are added, and users that
+// are no longer matched are removed, except for workspace admins and owners and users that are not checked
+// (bots, apps and deactivated users). Changes are only output in preview mode, and are refused if they exceed the configured
+// maximum number of changes.
+func (app *Application) SyncGroup(target string) error {
+
+	if app.Config.Output != "" || app.Config.Expire != "1963-11-04" {
+		return fmt.Errorf("sync-group does not support output or expire date filters")
+	}
+
+	usergroup := strings.HasPrefix(target, "S")
+	if !usergroup && !strings.HasPrefix(target, "C") && !strings.HasPrefix(target, "G") {
+		return fmt.Errorf("invalid user group or channel ID: %s", target)
+	}
+
+	mtl, err := app.MatchSlackMembers()
+	if err != nil {
+		return err
+	}
+
+	// Users that should be in the group, and users that may be removed from it
+	users := map[string]*Match{}
+	wanted := map[string]bool{}
+	for _, mt := range mtl {
+		users[mt.ID] = mt
+		for _, m := range mt.Members {
+			if m.Status == models.StatusActive || m.Status == models.StatusTrial {
+				wanted[mt.ID] = true
+				break
+			}
+		}
+	}
+
+	// Get current members of the user group or channel
+	var current []string
+	if usergroup {
+		current, err = app.SlackMemberAPI.UsergroupMembers(app.Ctx, target)
+	} else {
+		current, err = app.SlackMemberAPI.ChannelMembers(app.Ctx, target)
+	}
+	if err != nil {
+		app.ErrorLog.Printf("[SyncGroup] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[SyncGroup] %s has %d members, %d Slack users are matched to Active or Trial members", target, len(current), len(wanted))
+
+	// Determine users to add and remove
+	present := map[string]bool{}
+	keep := []string{}
+	remove := []string{}
+	for _, id := range current {
+		present[id] = true
+		mt, ok := users[id]
+		if wanted[id] || !ok || mt.Admin {
+			keep = append(keep, id)
+		} else {
+			remove = append(remove, id)
+		}
+	}
+
+	add := []string{}
+	for id := range wanted {
+		if !present[id] {
+			add = append(add, id)
+		}
+	}
+	sort.Slice(add, func(i, j int) bool {
+		return strings.ToLower(users[add[i]].FirstName) < strings.ToLower(users[add[j]].FirstName)
+	})
+
+	if app.Config.Preview {
+		app.InfoLog.Printf("[SyncGroup] Preview flag set: NOT making changes to %s \n", target)
+	}
+	app.InfoLog.Printf("[SyncGroup] %d users to add, %d users to remove \n\n", len(add), len(remove))
+
+	for _, id := range add {
+		fmt.Printf("+ %s \n", users[id].Label())
+	}
+	for _, id := range remove {
+		fmt.Printf("- %s \n", users[id].Label())
+	}
+
+	if app.Config.Preview || len(add)+len(remove) == 0 {
+		return nil
+	}
+
+	if len(add)+len(remove) > app.Config.Max {
+		return fmt.Errorf("%d changes exceed the maximum of %d, use -max to override", len(add)+len(remove), app.Config.Max)
+	}
+
+	// Apply changes, a user group is updated with its complete list of users
+	if usergroup {
+
+		err = app.SlackMemberAPI.UpdateUsergroup(app.Ctx, target, append(keep, add...))
+		if err != nil {
+			app.ErrorLog.Printf("[UpdateUsergroup] %s", err)
+			return err
+		}
+
+	} else {
+
+		if len(add) > 0 {
+			err = app.SlackMemberAPI.Invite(app.Ctx, target, add)
+			if err != nil {
+				app.ErrorLog.Printf("[Invite] %s", err)
+				return err
+			}
+		}
+
+		for _, id := range remove {
+			if err := app.Ctx.Err(); err != nil {
+				return err
+			}
+			err = app.SlackMemberAPI.Kick(app.Ctx, target, id)
+			if err != nil {
+				app.ErrorLog.Printf("[Kick] %s", err)
+				return err
+			}
+		}
+
+	}
+	app.InfoLog.Printf("[SyncGroup] Added %d and removed %d users of %s", len(add), len(remove), target)
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"svtc-sync/pkg/models"
+)
+
+func TestSyncGroupSkipsDeletedAndBots(t *testing.T) {
+
+	deleted := slackUser("U8", "Alice", "Warn", false)
+	deleted.Deleted = true
+	bot := slackUser("U9", "Bob", "Guest", false)
+	bot.Is_Bot = true
+
+	srv := newSlackServer(t, []models.Member{slackUser("U1", "Carol", "Deact", false), deleted, bot})
+	app := newPolicyApp(t, srv)
+	app.Config.Max = 10
+
+	for i, name := range []string{"Alice Warn", "Bob Guest", "Carol Deact"} {
+		n := strings.Fields(name)
+		m := &models.MemberSVTC{
+			Num:       fmt.Sprintf("%d001", i+1),
+			Active:    true,
+			FirstName: n[0],
+			LastName:  n[1],
+			Email:     strings.ToLower(n[0]) + "@example.com",
+			Status:    models.StatusActive,
+			Joined:    "2015-01-01",
+			Expired:   "2099-12-31",
+		}
+		err := app.MemberSQL.Insert(m)
+		if err != nil {
+			t.Fatalf("insert member failed: %s", err)
+		}
+	}
+
+	err := app.SyncGroup("S1")
+	if err != nil {
+		t.Fatalf("SyncGroup: %s", err)
+	}
+
+	updates := srv.called("usergroups.users.update")
+	if len(updates) != 1 || updates[0] != "U1" {
+		t.Errorf("user group updated with %q, expected U1 only", updates)
+	}
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Result of checking a single platform user (Slack workspace user or Strava club athlete) against the
+// reference DB. Members holds all matching member records incl. those found via the alias table.
+type Match struct {
+	Platform  string               // Source platform, i.e. slack or strava
+	ID        string               // Platform user ID, if provided by the platform (Slack)
+	FirstName string               // First name as provided by the platform
+	LastName  string               // Last name (or initial for Strava) as provided by the platform
+	Email     string               // Email as provided by the platform (Slack only)
+	Admin     bool                 // Platform user is a workspace admin or owner (Slack only)
+	TeamID    string               // Workspace ID of the platform user (Slack only)
+	Members   []*models.MemberSVTC // Matching member records, sorted by expiration date
+	Contact   *models.Outreach     // Most recent outreach within the configured window, if any
+}
+
+// Identity used to tie outreach records to a platform user; the email for Slack, full name for Strava.
+func (mt *Match) Identity() string {
+
+	if mt.Email != "" {
+		return strings.ToLower(mt.Email)
+	}
+
+	return strings.ToLower(strings.TrimSpace(mt.FirstName + " " + mt.LastName))
+}
+
+// Label of the platform user as used in check output, e.g. [Dave Scott (theman@gmail.com)]
+func (mt *Match) Label() string {
+
+	if mt.Email != "" {
+		return fmt.Sprintf("[%s %s (%s)]", mt.FirstName, mt.LastName, mt.Email)
+	}
+
+	return fmt.Sprintf("[%s %s]", mt.FirstName, mt.LastName)
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Match a Slack workspace user against the reference DB using (firstname AND lastname) OR email of the
+// member and alias tables, filtered by the configured status and expire date.
+func (app *Application) matchSlack(mSlack models.Member) (*Match, error) {
+
+	// Populate a new search member struct with query criteria
+	ms := models.MemberSVTC{
+		FirstName: strings.ToLower(mSlack.Profile.FirstName),
+		LastName:  strings.ToLower(mSlack.Profile.LastName),
+		Email:     strings.ToLower(mSlack.Profile.Email),
+		Status:    models.StatusMap[app.Config.Output],
+		Expired:   app.Config.Expire,
+	}
+
+	ml, err := app.match("slack", &ms)
+	if err != nil {
+		return nil, err
+	}
+
+	mt := &Match{
+		Platform:  "slack",
+		ID:        mSlack.ID,
+		FirstName: mSlack.Profile.FirstName,
+		LastName:  mSlack.Profile.LastName,
+		Email:     mSlack.Profile.Email,
+		Admin:     mSlack.Is_Admin || mSlack.Is_Owner,
+		TeamID:    mSlack.Team_ID,
+		Members:   ml,
+	}
+
+	return mt, nil
+}
+
+// Match a Strava club athlete against the reference DB using firstname AND initial of lastname of the
+// member and alias tables, filtered by the configured status and expire date.
+func (app *Application) matchStrava(mStrava models.Athlete) (*Match, error) {
+
+	// Populate a new member search struct with query criteria
+	ms := models.MemberSVTC{
+		FirstName: strings.ToLower(strings.TrimSpace(mStrava.FirstName)),
+		LastName:  strings.ToLower(strings.TrimSpace(string(mStrava.LastName[0]))),
+		Email:     string('_'),
+		Status:    models.StatusMap[app.Config.Output],
+		Expired:   app.Config.Expire,
+	}
+
+	ml, err := app.match("strava", &ms)
+	if err != nil {
+		return nil, err
+	}
+
+	mt := &Match{
+		Platform:  "strava",
+		FirstName: mStrava.FirstName,
+		LastName:  mStrava.LastName,
+		Members:   ml,
+	}
+
+	return mt, nil
+}
+
+// Query the member and alias tables with the search criteria and return the combined result set, sorted
+// by expiration date.
+func (app *Application) match(platform string, ms *models.MemberSVTC) ([]*models.MemberSVTC, error) {
+
+	// Query list of members from Sqlite3 DB using platform specific query criteria
+	ml, err := app.MemberSQL.ListMatch(platform, ms)
+	if err != nil {
+		app.ErrorLog.Printf("[ListMembers SQL] %s", err)
+		return nil, err
+	}
+
+	// Query alias table for members using same search criteria
+	ma, err := app.MemberSQL.GetAlias(ms)
+	if err != nil {
+		app.ErrorLog.Printf("[Alias SQL] %s", err)
+		return nil, err
+	}
+
+	// Append matches from alias table to result set
+	ml = append(ml, ma...)
+
+	// Sort results of comparison by expiration date
+	app.sort(ml, "exp")
+
+	return ml, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Print the result of a match in a format that is determined by configuration settings. Platform users that
+// have been contacted recently are suppressed, or marked when the mark option is set.
+func (app *Application) printMatch(mt *Match) {
+
+	label := mt.Label()
+
+	if mt.Contact != nil {
+		if !app.Config.Mark || app.Config.Email {
+			return
+		}
+		label += fmt.Sprintf(" (contacted %s via %s)", mt.Contact.Date, mt.Contact.Channel)
+	}
+
+	// Determine output based on configuration settings and print results of comparison
+	switch app.Config.Output {
+
+	case "NF":
+
+		// Print record not found in reference data
+		if len(mt.Members) == 0 {
+			fmt.Printf("%s Not Found \n", label)
+		}
+
+	case "DUP":
+
+		if len(mt.Members) > 1 {
+			// Print all records where there is more than one match, grouped by platform user record
+			fmt.Printf("%s \n", label)
+			for _, m := range mt.Members {
+				fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
+			}
+		}
+
+	case "ACT", "EXP", "DRO", "FRO", "BUL", "PEN", "PRO", "TRI":
+
+		// Print records that have the selected status. When email flag is set, print in RFC 5322 format
+		if len(mt.Members) > 0 {
+			if !app.Config.Email {
+				fmt.Printf("%s \n", label)
+			}
+			for _, m := range mt.Members {
+				if app.Config.Email {
+					fmt.Printf("%s %s <%s>,\n", m.FirstName, m.LastName, m.Email)
+				} else {
+					fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
+				}
+			}
+		}
+
+	default:
+
+		// Print all records (incl. duplicates and not found) grouped by platform user record
+		fmt.Printf("%s \n", label)
+		for _, m := range mt.Members {
+			fmt.Printf("\t[%s] %s %s (%s) - %s [%s] \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
+		}
+
+	}
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+	"svtc-sync/pkg/models/sqlite"
+)
+
+// Entry of the history of a member record, i.e. an outreach, policy action, merge or edit
+type memberEvent struct {
+	Date   string
+	Kind   string
+	Detail string
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Print all columns of the member record of a member number, incl. an invalid record, along with its aliases,
+// the platform users matched to it in the most recent check run per platform, and its history of outreach,
+// Slack policy actions, merges and edits, most recent first.
+func (app *Application) ShowMember(num string) error {
+
+	m, err := app.MemberSQL.GetRecord(num)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("member %s not found", num)
+		}
+		app.ErrorLog.Printf("[GetRecord SQL] %s", err)
+		return err
+	}
+
+	active := "1 (valid)"
+	if !m.Active {
+		active = "0 (invalid)"
+	}
+
+	fmt.Printf("Member [%s] %s %s \n", m.Num, m.FirstName, m.LastName)
+	for _, f := range [][2]string{
+		{"id", fmt.Sprint(m.ID)}, {"num", m.Num}, {"active", active}, {"login", m.Login},
+		{"firstname", m.FirstName}, {"middle", m.Middle}, {"lastname", m.LastName}, {"email", m.Email},
+		{"status", string(m.Status)}, {"joined", m.Joined}, {"expired", m.Expired},
+		{"address", m.Address}, {"addr_ext", m.AddrExt}, {"city", m.City}, {"state", m.State}, {"zip", m.Zip},
+		{"mobile", m.Mobile}, {"phone", m.Phone},
+	} {
+		fmt.Printf("  %-10s %s \n", f[0], f[1])
+	}
+
+	// Aliases
+	al, err := app.MemberSQL.ListAliases()
+	if err != nil {
+		app.ErrorLog.Printf("[ListAliases SQL] %s", err)
+		return err
+	}
+	fmt.Printf("\nAliases \n")
+	for _, a := range al {
+		if a.MemberID == m.ID {
+			fmt.Printf("  alias %d: %s %s %s \n", a.ID, a.FirstName, a.LastName, a.Email)
+		}
+	}
+
+	// Platform links, i.e. platform users matched to the member in the latest check run
+	fmt.Printf("\nPlatform links \n")
+	for _, platform := range []string{"slack", "strava"} {
+
+		rl, err := app.RunSQL.List(platform, 1)
+		if err != nil {
+			app.ErrorLog.Printf("[Run SQL] %s", err)
+			return err
+		}
+
+		for _, run := range rl {
+			ul, err := app.RunSQL.Users(run.ID)
+			if err != nil {
+				app.ErrorLog.Printf("[Run SQL] %s", err)
+				return err
+			}
+			for _, u := range ul {
+				if u.Num == m.Num {
+					fmt.Printf("  %-6s %s (%s) - run %s \n", platform, u.Identity, u.Name, run.Date)
+				}
+			}
+		}
+	}
+
+	// History
+	el := []*memberEvent{}
+
+	ol, err := app.OutreachSQL.List("")
+	if err != nil {
+		app.ErrorLog.Printf("[Outreach SQL] %s", err)
+		return err
+	}
+	for _, o := range ol {
+		if o.Num == m.Num {
+			el = append(el, &memberEvent{o.Date, "outreach", strings.TrimSpace(fmt.Sprintf("%s %s %s %s %s", o.Channel, o.Platform, o.Identity, o.Template, o.Notes))})
+		}
+	}
+
+	acl, err := app.ActionSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[Action SQL] %s", err)
+		return err
+	}
+	for _, a := range acl {
+		if a.Num == m.Num {
+			el = append(el, &memberEvent{a.Date, "action", fmt.Sprintf("%s %s: %s (%s)", a.Action, a.Identity, a.Reason, a.Result)})
+		}
+	}
+
+	mml, err := app.MergeSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[Merge SQL] %s", err)
+		return err
+	}
+	for _, mm := range mml {
+		if mm.KeepNum == m.Num {
+			el = append(el, &memberEvent{mm.Date, "merge", fmt.Sprintf("[%s] merged into this record", mm.DropNum)})
+		}
+		if mm.DropNum == m.Num {
+			el = append(el, &memberEvent{mm.Date, "merge", fmt.Sprintf("merged into [%s]", mm.KeepNum)})
+		}
+	}
+
+	edl, err := app.MemberEditSQL.List(m.Num)
+	if err != nil {
+		app.ErrorLog.Printf("[MemberEdit SQL] %s", err)
+		return err
+	}
+	for _, e := range edl {
+		el = append(el, &memberEvent{e.Date, "edit", strings.TrimSpace(fmt.Sprintf("%s: %q -> %q %s", e.Field, e.Old, e.New, e.Notes))})
+	}
+
+	sort.SliceStable(el, func(i, j int) bool { return el[i].Date > el[j].Date })
+
+	fmt.Printf("\nHistory \n")
+	for _, e := range el {
+		fmt.Printf("  %-19s %-8s %s \n", e.Date, e.Kind, e.Detail)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Edit fields of the member record of a member number, specified as --field=value arguments with the column
+// names of the member table, e.g. --email=theman@gmail.com. Values are validated, and all edits are logged along
+// with the notes option. With the preview option the edits are listed, but not made.
+func (app *Application) EditMember(num string, args []string) error {
+
+	if len(args) == 0 {
+		return fmt.Errorf("no fields to edit, expected --field=value")
+	}
+
+	edits := []*models.MemberEdit{}
+	for _, arg := range args {
+
+		kv := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid argument %q, expected --field=value", arg)
+		}
+		field := strings.ToLower(kv[0])
+
+		value, err := validateEdit(field, strings.TrimSpace(kv[1]))
+		if err != nil {
+			return err
+		}
+
+		edits = append(edits, &models.MemberEdit{Field: field, New: value, Notes: app.Config.Notes})
+	}
+
+	return app.applyEdits(num, edits)
+}
+
+// Set the active flag of the member record of a member number, i.e. deactivate a record to keep it for
+// reference only, or reactivate it. A record merged into another one can not be reactivated.
+func (app *Application) SetMemberActive(num string, active bool) error {
+
+	value := "0"
+	if active {
+		value = "1"
+
+		redirects, err := app.MergeSQL.Redirects()
+		if err != nil {
+			app.ErrorLog.Printf("[Merge SQL] %s", err)
+			return err
+		}
+		if keep, ok := redirects[num]; ok {
+			return fmt.Errorf("member %s was merged into %s and can not be reactivated", num, keep)
+		}
+	}
+
+	return app.applyEdits(num, []*models.MemberEdit{{Field: "active", New: value, Notes: app.Config.Notes}})
+}
+
+func (app *Application) applyEdits(num string, edits []*models.MemberEdit) error {
+
+	if app.Config.Preview {
+		app.InfoLog.Printf("[EditMember] Preview flag set: NOT making changes to DB \n")
+	}
+
+	applied, err := app.MemberEditSQL.Apply(num, edits, app.Config.Preview)
+	if err != nil {
+		app.ErrorLog.Printf("[MemberEdit SQL] %s", err)
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Printf("[%s] unchanged \n", num)
+	}
+	for _, e := range applied {
+		fmt.Printf("[%s] %s: %q -> %q \n", num, e.Field, e.Old, e.New)
+	}
+
+	return nil
+}
+
+// Validate the value of an edited field, returns the value to store, e.g. the status name for a status code
+func validateEdit(field string, value string) (string, error) {
+
+	switch field {
+
+	case "active":
+		return "", fmt.Errorf("the active flag is set with member deactivate or reactivate")
+
+	case "firstname", "lastname":
+		if value == "" {
+			return "", fmt.Errorf("%s must not be empty", field)
+		}
+
+	case "email":
+		if !validEmail(value) {
+			return "", fmt.Errorf("invalid email %q", value)
+		}
+
+	case "status":
+		if s, ok := models.StatusMap[strings.ToUpper(value)]; ok {
+			return string(s), nil
+		}
+		if !models.Status(value).Valid() {
+			return "", fmt.Errorf("%w: %q", models.ErrInvalidStatus, value)
+		}
+
+	case "joined", "expired":
+		if helpers.GetDate(value).IsZero() {
+			return "", fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", field, value)
+		}
+
+	default:
+		for _, c := range sqlite.EditColumns {
+			if c == field {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(sqlite.EditColumns, ", "))
+	}
+
+	return value, nil
+}
+
+// --------------------------------------------------------------------------------------------
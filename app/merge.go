@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fmt"
+
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Merge the duplicate member record of the drop number into the record of the keep number, e.g. after a member
+// re-registered in ClubExpress. Aliases and history are moved to the kept record, the dropped record is marked
+// invalid, and future imports of the drop number are redirected to the keep number. With the preview option
+// the changes are listed, but not made.
+func (app *Application) Merge(keepNum string, dropNum string) error {
+
+	mm := &models.MemberMerge{
+		KeepNum: keepNum,
+		DropNum: dropNum,
+	}
+
+	changed, err := app.MergeSQL.Merge(mm, app.Config.Preview)
+	if err != nil {
+		app.ErrorLog.Printf("[Merge SQL] %s", err)
+		return err
+	}
+
+	if app.Config.Preview {
+		app.InfoLog.Printf("[Merge] Preview flag set: NOT making changes to DB \n")
+	}
+
+	fmt.Printf("Merged [%s] into [%s], rows changed: \n", mm.DropNum, mm.KeepNum)
+	for _, table := range []string{"member", "alias", "outreach", "action", "run_user", "member_edit", "member_merge"} {
+		fmt.Printf("  %-14s %6d\n", table, changed[table])
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+
+	"svtc-sync/pkg/models"
+	"svtc-sync/pkg/models/sqlite"
+)
+
+func newMergeApp(t *testing.T) *Application {
+
+	db, _ := newTestDB(t)
+
+	_, err := db.Exec("CREATE TABLE member (id INTEGER PRIMARY KEY, num TEXT, active INTEGER, login TEXT, firstname TEXT, middle TEXT, lastname TEXT, email TEXT, status TEXT, joined TEXT, expired TEXT, address TEXT, addr_ext TEXT, city TEXT, state TEXT, zip TEXT, mobile TEXT, phone TEXT)")
+	if err != nil {
+		t.Fatalf("create table failed: %s", err)
+	}
+	_, err = db.Exec("CREATE TABLE alias (id INTEGER PRIMARY KEY, memberid INTEGER, firstname TEXT, lastname TEXT, email TEXT)")
+	if err != nil {
+		t.Fatalf("create table failed: %s", err)
+	}
+
+	app := &Application{
+		ErrorLog:      log.New(ioutil.Discard, "", 0),
+		InfoLog:       log.New(ioutil.Discard, "", 0),
+		Config:        &Configuration{},
+		MemberSQL:     &sqlite.MemberModel{DB: db},
+		OutreachSQL:   &sqlite.OutreachModel{DB: db},
+		RunSQL:        &sqlite.RunModel{DB: db},
+		ActionSQL:     &sqlite.ActionModel{DB: db},
+		MergeSQL:      &sqlite.MergeModel{DB: db},
+		MemberEditSQL: &sqlite.MemberEditModel{DB: db},
+	}
+
+	for _, err := range []error{app.OutreachSQL.Init(), app.RunSQL.Init(), app.ActionSQL.Init(), app.MergeSQL.Init(), app.MemberEditSQL.Init()} {
+		if err != nil {
+			t.Fatalf("init db failed: %s", err)
+		}
+	}
+
+	return app
+}
+
+func insertMember(t *testing.T, app *Application, num string, email string, status models.Status, joined string, expired string) {
+
+	m := &models.MemberSVTC{Num: num, Active: true, FirstName: "Dave", LastName: "Scott", Email: email, Status: status, Joined: joined, Expired: expired}
+	err := app.MemberSQL.Insert(m)
+	if err != nil {
+		t.Fatalf("insert member failed: %s", err)
+	}
+}
+
+// --------------------------------------------------------------------------------------------
+
+func TestMergeStatus(t *testing.T) {
+
+	app := newMergeApp(t)
+	insertMember(t, app, "1001", "dave@example.com", models.StatusExpired, "2015-01-01", "2020-12-31")
+	insertMember(t, app, "2001", "dave@example.net", models.StatusActive, "2021-03-01", "2022-12-31")
+
+	_, err := app.MemberEditSQL.Apply("2001", []*models.MemberEdit{{Field: "email", New: "dave@example.org", Notes: "typo"}}, false)
+	if err != nil {
+		t.Fatalf("edit member failed: %s", err)
+	}
+
+	err = app.Merge("1001", "2001")
+	if err != nil {
+		t.Fatalf("Merge: %s", err)
+	}
+
+	edl, err := app.MemberEditSQL.List("1001")
+	if err != nil {
+		t.Fatalf("list edits failed: %s", err)
+	}
+	if len(edl) != 1 || edl[0].Field != "email" {
+		t.Errorf("edits of the kept record %v, expected the edit of the dropped record", edl)
+	}
+
+	m, err := app.MemberSQL.GetRecord("1001")
+	if err != nil {
+		t.Fatalf("get member failed: %s", err)
+	}
+	if m.Status != models.StatusActive || m.Joined != "2015-01-01" || m.Expired != "2022-12-31" {
+		t.Errorf("kept record %s %s-%s, expected Active 2015-01-01-2022-12-31", m.Status, m.Joined, m.Expired)
+	}
+
+	mml, err := app.MergeSQL.List()
+	if err != nil {
+		t.Fatalf("list merges failed: %s", err)
+	}
+	if len(mml) != 1 || len(mml[0].Date) != len("2006-01-02 15:04:05") {
+		t.Errorf("merges %v, expected one with the date and time of the merge", mml)
+	}
+}
+
+func TestMergeIllegalStatus(t *testing.T) {
+
+	app := newMergeApp(t)
+	insertMember(t, app, "1001", "dave@example.com", models.StatusActive, "2015-01-01", "2020-12-31")
+	insertMember(t, app, "2001", "dave@example.net", models.StatusProspective, "2021-03-01", "2022-12-31")
+
+	err := app.Merge("1001", "2001")
+	if !errors.Is(err, models.ErrIllegalTransition) {
+		t.Fatalf("Merge: expected illegal transition, got %v", err)
+	}
+
+	m, err := app.MemberSQL.GetRecord("2001")
+	if err != nil {
+		t.Fatalf("get member failed: %s", err)
+	}
+	if !m.Active {
+		t.Errorf("dropped record marked invalid, expected the failed merge to be rolled back")
+	}
+}
+
+func TestMergeInvalidDrop(t *testing.T) {
+
+	app := newMergeApp(t)
+	insertMember(t, app, "1001", "dave@example.com", models.StatusActive, "2015-01-01", "2022-12-31")
+	insertMember(t, app, "2001", "dave@example.net", models.StatusExpired, "2021-03-01", "2021-12-31")
+
+	_, err := app.MemberSQL.DB.Exec("UPDATE member SET active = 0 WHERE num = ?", "2001")
+	if err != nil {
+		t.Fatalf("deactivate member failed: %s", err)
+	}
+
+	err = app.Merge("1001", "2001")
+	if err == nil || !strings.Contains(err.Error(), "invalid already") {
+		t.Fatalf("Merge: expected invalid drop record error, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Send a direct message, rendered from the configured template, to each Slack workspace user matched to a
+// member with the selected status (Expired or Trial). Users that have been messaged on Slack within the
+// recent window (or today if not set) are skipped, messages are spaced out according to the configured
+// rate and each message sent is recorded as an outreach record. In preview mode messages are rendered and
+// output, but neither sent nor recorded.
+func (app *Application) NotifySlack() error {
+
+	if app.Config.Output != "EXP" && app.Config.Output != "TRI" {
+		return fmt.Errorf("notify requires an output filter of EXP or TRI")
+	}
+
+	t, name, err := app.template(defaultTemplateName, defaultTemplate)
+	if err != nil {
+		app.ErrorLog.Printf("[template] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[NotifySlack] Using template %s \n", name)
+
+	// Get platform identities of users that have been messaged on Slack recently
+	since := time.Now().Local().AddDate(0, 0, -app.Config.Recent).Format("2006-01-02")
+	ol, err := app.OutreachSQL.List(since)
+	if err != nil {
+		app.ErrorLog.Printf("[Outreach SQL] %s", err)
+		return err
+	}
+	messaged := map[string]string{}
+	for _, o := range ol {
+		if o.Platform == "slack" && o.Channel == "slack" {
+			if _, ok := messaged[o.Identity]; !ok {
+				messaged[o.Identity] = o.Date
+			}
+		}
+	}
+
+	mtl, err := app.MatchSlackMembers()
+	if err != nil {
+		return err
+	}
+
+	if app.Config.Preview {
+		app.InfoLog.Printf("[NotifySlack] Preview flag set: NOT sending messages \n")
+	}
+	app.InfoLog.Printf("[NotifySlack] Messaging matched Slack users at %d per minute \n\n", app.Config.Rate)
+
+	// Space out messages according to the configured rate
+	interval := time.Minute / time.Duration(app.Config.Rate)
+	var last time.Time
+
+	var nSent, nSkipped, nFailed int
+
+	for _, mt := range mtl {
+
+		if len(mt.Members) == 0 {
+			continue
+		}
+
+		if mt.Contact != nil {
+			fmt.Printf("%s - skipped (contacted %s) \n", mt.Label(), mt.Contact.Date)
+			nSkipped++
+			continue
+		}
+
+		if d, ok := messaged[mt.Identity()]; ok {
+			fmt.Printf("%s - skipped (messaged %s) \n", mt.Label(), d)
+			nSkipped++
+			continue
+		}
+
+		// Use the member record with the latest expiration date
+		m := mt.Members[0]
+
+		msg, err := render(t, m)
+		if err != nil {
+			app.ErrorLog.Printf("[render] %s: %s", m.Num, err)
+			return err
+		}
+
+		if app.Config.Preview {
+			fmt.Printf("%s \n", mt.Label())
+			fmt.Printf("%s \n", msg.Body)
+			continue
+		}
+
+		if wait := interval - time.Since(last); wait > 0 {
+			err = app.sleep(wait)
+			if err != nil {
+				return err
+			}
+		}
+		last = time.Now()
+
+		channel, err := app.SlackMemberAPI.OpenDM(app.Ctx, mt.ID)
+		if err == nil {
+			err = app.SlackMemberAPI.PostMessage(app.Ctx, channel, msg.Body)
+		}
+		if err != nil {
+			fmt.Printf("%s - failed \n", mt.Label())
+			app.ErrorLog.Printf("[NotifySlack] %s: %s", mt.ID, err)
+			nFailed++
+			continue
+		}
+		fmt.Printf("%s - sent \n", mt.Label())
+		nSent++
+
+		o := &models.Outreach{
+			Num:      m.Num,
+			Platform: "slack",
+			Identity: mt.Identity(),
+			Channel:  "slack",
+			Date:     time.Now().Local().Format("2006-01-02"),
+			Template: name,
+			Notes:    "direct message",
+		}
+		err = app.OutreachSQL.Insert(o)
+		if err != nil {
+			app.ErrorLog.Printf("[Insert] %s", err)
+			return err
+		}
+
+	}
+
+	app.InfoLog.Printf("[NotifySlack] Sent %d, skipped %d, failed %d messages \n", nSent, nSkipped, nFailed)
+
+	if nFailed > 0 {
+		return fmt.Errorf("%d messages failed, re-run to resume", nFailed)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
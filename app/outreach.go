@@ -0,0 +1,162 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Index of recent outreach records, keyed by member number ("num:1234") and by platform identity
+// ("slack:theman@gmail.com"). Only the most recent record per key is kept.
+type contactIndex map[string]*models.Outreach
+
+// Find the most recent outreach for a platform user, either via their platform identity or via any of
+// their matched member records. Returns nil if there was no contact (or the index is empty).
+func (ci contactIndex) find(mt *Match) *models.Outreach {
+
+	if len(ci) == 0 {
+		return nil
+	}
+
+	if o, ok := ci[mt.Platform+":"+mt.Identity()]; ok {
+		return o
+	}
+
+	for _, m := range mt.Members {
+		if o, ok := ci["num:"+m.Num]; ok {
+			return o
+		}
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Build an index of outreach records within the number of days specified via the recent option. Returns
+// an empty index if the option is not set.
+func (app *Application) recentOutreach() (contactIndex, error) {
+
+	ci := contactIndex{}
+
+	if app.Config.Recent <= 0 {
+		return ci, nil
+	}
+
+	since := time.Now().Local().AddDate(0, 0, -app.Config.Recent).Format("2006-01-02")
+
+	// Records are returned most recent first, i.e. keep the first one found per key
+	ol, err := app.OutreachSQL.List(since)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range ol {
+		if o.Num != "" {
+			if _, ok := ci["num:"+o.Num]; !ok {
+				ci["num:"+o.Num] = o
+			}
+		}
+		if o.Identity != "" {
+			key := o.Platform + ":" + o.Identity
+			if _, ok := ci[key]; !ok {
+				ci[key] = o
+			}
+		}
+	}
+
+	return ci, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (app *Application) ListOutreach() error {
+
+	// Query outreach table for all records, most recent first
+	ol, err := app.OutreachSQL.List("")
+	if err != nil {
+		app.ErrorLog.Printf("[Outreach SQL] %s", err)
+		return err
+	}
+
+	for _, o := range ol {
+		who := o.Num
+		if o.Identity != "" {
+			who = strings.TrimSpace(fmt.Sprintf("%s %s:%s", o.Num, o.Platform, o.Identity))
+		}
+		fmt.Printf("%s [%s] via %s - %s %s \n", o.Date, who, o.Channel, o.Template, o.Notes)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Log a contact against a member number or a platform identity. Arguments are expected as either
+//
+//	add <num>
+//	add (slack|strava) <identity>
+//
+// with channel, template, notes and date taken from configuration options.
+func (app *Application) AddOutreach(args []string) error {
+
+	o := &models.Outreach{
+		Channel:  app.Config.Via,
+		Date:     app.Config.Date,
+		Template: app.Config.Reason,
+		Notes:    app.Config.Notes,
+	}
+
+	if o.Date == "" {
+		o.Date = time.Now().Local().Format("2006-01-02")
+	}
+	if helpers.GetDate(o.Date).IsZero() {
+		return fmt.Errorf("invalid outreach date: %s", o.Date)
+	}
+
+	switch {
+
+	case len(args) == 2 && args[0] == "add":
+
+		// Ensure the member number exists in the reference DB
+		_, err := app.MemberSQL.Get(args[1])
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("no member record found: %s", args[1])
+			}
+			app.ErrorLog.Printf("[Get] %s", err)
+			return err
+		}
+		o.Num = args[1]
+
+	case len(args) >= 3 && args[0] == "add" && (args[1] == "slack" || args[1] == "strava"):
+
+		// Identities are stored in lower case to match the check output, e.g. "dave s." for Strava
+		o.Platform = args[1]
+		o.Identity = strings.ToLower(strings.TrimSpace(strings.Join(args[2:], " ")))
+
+	default:
+		return fmt.Errorf("invalid outreach arguments: %s", strings.Join(args, " "))
+
+	}
+
+	err := app.OutreachSQL.Insert(o)
+	if err != nil {
+		app.ErrorLog.Printf("[Insert] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[AddOutreach] Logged contact via %s on %s", o.Channel, o.Date)
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
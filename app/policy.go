@@ -0,0 +1,321 @@
+package app
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Built-in warning template for the Slack policy, executed with the matched member record (or a record holding
+// only the first name of the Slack user if not found).
+const warnTemplate = `Hi {{.FirstName}},
+
+the SVTC Slack workspace is a benefit for club members. {{if .Expired}}Our records show that your membership expired on {{date .Expired}}.{{else}}We could not find a club membership for you.{{end}}
+Please renew at https://www.svtc.org to keep full access to the workspace, or reply here if you think this is a mistake.
+
+SVTC Membership
+`
+
+// Name recorded for the built-in warning template
+const warnTemplateName = "policy-warning"
+
+// Policy actions in the order of escalation
+var policyActions = []string{"warn", "guest", "deactivate"}
+
+// --------------------------------------------------------------------------------------------
+
+// Apply the Slack policy to workspace users that are matched to members Expired since before the grace period,
+// or that have not been found in the configured number of consecutive check runs. Such users are warned by
+// direct message first. If the warning is older than the wait period, the next action up to the configured
+// maximum (guest or deactivate) is taken via the Slack admin api. Escalation only continues from actions of the
+// current lapse, i.e. after the member's expiry and after the user was last matched to an Active member. Admins
+// and owners, as well as bots and deactivated users, are never subject to the policy. Each action is logged in the action log; in preview mode actions are only output.
+func (app *Application) ApplyPolicy() error {
+
+	if app.Config.Output != "" || app.Config.Expire != "1963-11-04" {
+		return fmt.Errorf("policy does not support output or expire date filters")
+	}
+
+	maxLevel := -1
+	for i, a := range policyActions {
+		if a == app.Config.Action {
+			maxLevel = i
+		}
+	}
+	if maxLevel < 0 {
+		return fmt.Errorf("invalid policy action: %s", app.Config.Action)
+	}
+
+	t, name, err := app.template(warnTemplateName, warnTemplate)
+	if err != nil {
+		app.ErrorLog.Printf("[template] %s", err)
+		return err
+	}
+
+	// Admin actions require a separate user token, only warnings are possible without it
+	if maxLevel > 0 {
+		admin_token, err := app.Creds.GetSlackAdminAccess()
+		if err != nil {
+			app.ErrorLog.Printf("[GetSlackAdminAccess] Unable to read Slack admin credentials %s", err)
+			return err
+		}
+		if admin_token == "" {
+			app.InfoLog.Printf("[ApplyPolicy] No Slack admin token configured, only warnings will be sent")
+			maxLevel = 0
+		}
+	}
+
+	mtl, err := app.MatchSlackMembers()
+	if err != nil {
+		return err
+	}
+
+	notFound, err := app.notFoundRuns(mtl, app.Config.Runs)
+	if err != nil {
+		return err
+	}
+
+	// Store a snapshot of this run, so that it counts towards consecutive runs of users not found
+	if !app.Config.Preview {
+		_, err = app.summarize("slack", mtl, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Most recent successful action per Slack user
+	al, err := app.ActionSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[Action SQL] %s", err)
+		return err
+	}
+	last := map[string]*models.Action{}
+	for _, a := range al {
+		if _, ok := last[a.UserID]; !ok && a.Result == "ok" {
+			last[a.UserID] = a
+		}
+	}
+
+	// Most recent check run per Slack user, in which the user was matched to an Active member
+	active, err := app.RunSQL.LastMatched("slack", string(models.StatusActive))
+	if err != nil {
+		app.ErrorLog.Printf("[Run SQL] %s", err)
+		return err
+	}
+
+	today := time.Now().Local()
+	cutoff := today.AddDate(0, 0, -app.Config.Grace).Format("2006-01-02")
+	waited := today.AddDate(0, 0, -app.Config.Wait).Format("2006-01-02")
+
+	if app.Config.Preview {
+		app.InfoLog.Printf("[ApplyPolicy] Preview flag set: NOT taking any actions \n")
+	}
+	app.InfoLog.Printf("[ApplyPolicy] Applying policy to users expired before %s or not found in runs on %d consecutive days \n\n", cutoff, app.Config.Runs)
+
+	for _, mt := range mtl {
+
+		if err := app.Ctx.Err(); err != nil {
+			return err
+		}
+
+		if mt.Admin {
+			continue
+		}
+
+		// Determine whether the policy applies to the user
+		var reason string
+		m := &models.MemberSVTC{FirstName: mt.FirstName}
+
+		if len(mt.Members) == 0 {
+			if !notFound[mt.Identity()] {
+				continue
+			}
+			reason = fmt.Sprintf("not found in runs on %d days", app.Config.Runs)
+		} else {
+			m = mt.Members[0]
+			if m.Status != models.StatusExpired || m.Expired >= cutoff {
+				continue
+			}
+			reason = fmt.Sprintf("[%s] expired %s", m.Num, m.Expired)
+		}
+
+		// Start of the current lapse, i.e. the member's expiry or the day after the user was last matched to an
+		// Active member. Actions before it belong to an earlier lapse, and the policy starts over with a warning.
+		lapse := m.Expired
+		if day := active[mt.Identity()]; len(day) >= 10 && day[:10] >= lapse {
+			lapse = helpers.GetDate(day[:10]).AddDate(0, 0, 1).Format("2006-01-02")
+		}
+
+		// Determine the next action, i.e. escalate once the wait period after the last action has passed
+		level := 0
+		if a, ok := last[mt.ID]; ok && a.Date >= lapse {
+			for i, pa := range policyActions {
+				if pa == a.Action {
+					level = i + 1
+				}
+			}
+			if level > maxLevel || a.Date > waited {
+				fmt.Printf("%s %s - waiting (%s %s) \n", mt.Label(), reason, a.Action, a.Date)
+				continue
+			}
+		}
+		action := policyActions[level]
+
+		fmt.Printf("%s %s -> %s \n", mt.Label(), reason, action)
+
+		if app.Config.Preview {
+			continue
+		}
+
+		var actErr error
+
+		switch action {
+
+		case "warn":
+			actErr = app.directMessage(t, m, mt.ID)
+
+		case "guest":
+			actErr = app.SlackMemberAPI.SetGuest(app.Ctx, mt.TeamID, mt.ID)
+
+		case "deactivate":
+			actErr = app.SlackMemberAPI.Deactivate(app.Ctx, mt.TeamID, mt.ID)
+
+		}
+
+		a := &models.Action{
+			Date:     today.Format("2006-01-02"),
+			UserID:   mt.ID,
+			Identity: mt.Identity(),
+			Num:      m.Num,
+			Action:   action,
+			Reason:   reason,
+			Result:   "ok",
+		}
+		if actErr != nil {
+			app.ErrorLog.Printf("[ApplyPolicy] %s %s: %s", action, mt.ID, actErr)
+			a.Result = actErr.Error()
+		}
+		if action == "warn" {
+			a.Reason += " (" + name + ")"
+		}
+
+		err = app.ActionSQL.Insert(a)
+		if err != nil {
+			app.ErrorLog.Printf("[Insert] %s", err)
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Render the template for a member and send it to a Slack user via direct message
+func (app *Application) directMessage(t *template.Template, m *models.MemberSVTC, user_id string) error {
+
+	msg, err := render(t, m)
+	if err != nil {
+		return err
+	}
+
+	channel, err := app.SlackMemberAPI.OpenDM(app.Ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	return app.SlackMemberAPI.PostMessage(app.Ctx, channel, msg.Body)
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Return the identities of Slack users that are Not Found in the matches of the current run, and in the
+// snapshots of the runs on the previous days, for the specified number of days in total. Only the most recent
+// snapshot of a day counts, so that repeated runs on the same day do not escalate the policy. Returns an empty
+// set if there are fewer days with snapshots.
+func (app *Application) notFoundRuns(mtl []*Match, runs int) (map[string]bool, error) {
+
+	nf := map[string]bool{}
+	if runs <= 0 {
+		return nf, nil
+	}
+
+	for _, mt := range mtl {
+		if len(mt.Members) == 0 {
+			nf[mt.Identity()] = true
+		}
+	}
+
+	rl, err := app.RunSQL.List("slack", 0)
+	if err != nil {
+		app.ErrorLog.Printf("[Run SQL] %s", err)
+		return nil, err
+	}
+
+	// Most recent run per day before today, runs are listed most recent first
+	days := map[string]bool{time.Now().Local().Format("2006-01-02"): true}
+	previous := []*models.Run{}
+	for _, run := range rl {
+		if len(previous) == runs-1 {
+			break
+		}
+		day := run.Date
+		if len(day) > 10 {
+			day = day[:10]
+		}
+		if days[day] {
+			continue
+		}
+		days[day] = true
+		previous = append(previous, run)
+	}
+	if len(previous) < runs-1 {
+		return map[string]bool{}, nil
+	}
+
+	for _, run := range previous {
+		ul, err := app.RunSQL.Users(run.ID)
+		if err != nil {
+			app.ErrorLog.Printf("[Run SQL] %s", err)
+			return nil, err
+		}
+		notFound := map[string]bool{}
+		for _, u := range ul {
+			if u.Status == statusNotFound {
+				notFound[u.Identity] = true
+			}
+		}
+		for id := range nf {
+			if !notFound[id] {
+				delete(nf, id)
+			}
+		}
+	}
+
+	return nf, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (app *Application) ListActions() error {
+
+	al, err := app.ActionSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[Action SQL] %s", err)
+		return err
+	}
+
+	for _, a := range al {
+		fmt.Printf("%s [%s %s] %s - %s: %s \n", a.Date, a.UserID, a.Identity, a.Action, a.Reason, a.Result)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,318 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"svtc-sync/pkg/models"
+	"svtc-sync/pkg/models/api"
+	"svtc-sync/pkg/models/sqlite"
+)
+
+// Stand-in of the Slack web api, that lists the workspace users and records the calls of all other methods by
+// method name with the user ID of the call, or the text of a slash command response posted to /response
+type slackServer struct {
+	*httptest.Server
+	users []models.Member
+	mu    sync.Mutex
+	calls map[string][]string
+}
+
+func newSlackServer(t *testing.T, users []models.Member) *slackServer {
+
+	s := &slackServer{users: users, calls: map[string][]string{}}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		if method == "users.list" {
+			json.NewEncoder(w).Encode(models.ResponseMember{Ok: true, Members: s.users})
+			return
+		}
+		if method == "users.info" {
+			for _, u := range s.users {
+				if u.ID == r.URL.Query().Get("user") {
+					json.NewEncoder(w).Encode(models.ResponseUser{Ok: true, User: u})
+					return
+				}
+			}
+			json.NewEncoder(w).Encode(models.Response{Ok: false, Error: "user_not_found"})
+			return
+		}
+
+		payload := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		user := payload["user_id"]
+		if method == "response" {
+			user = payload["text"]
+		}
+		if method == "conversations.open" || method == "usergroups.users.update" {
+			user = payload["users"]
+		}
+		if method == "chat.postMessage" {
+			user = strings.TrimPrefix(payload["channel"], "D-")
+		}
+
+		s.mu.Lock()
+		s.calls[method] = append(s.calls[method], user)
+		s.mu.Unlock()
+
+		if method == "conversations.open" {
+			json.NewEncoder(w).Encode(models.ResponseChannel{Ok: true, Channel: models.Channel{ID: "D-" + user}})
+			return
+		}
+		json.NewEncoder(w).Encode(models.Response{Ok: true})
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *slackServer) called(method string) []string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.calls[method]...)
+}
+
+func slackUser(id string, first string, last string, admin bool) models.Member {
+
+	return models.Member{
+		ID:                 id,
+		Team_ID:            "T1",
+		Profile:            models.Profile{FirstName: first, LastName: last, Email: strings.ToLower(first) + "@example.com"},
+		Is_Email_Confirmed: true,
+		Is_Admin:           admin,
+	}
+}
+
+func newPolicyApp(t *testing.T, srv *slackServer) *Application {
+
+	db, dir := newTestDB(t)
+
+	for _, query := range []string{
+		"CREATE TABLE member (id INTEGER PRIMARY KEY, num TEXT, active INTEGER, login TEXT, firstname TEXT, middle TEXT, lastname TEXT, email TEXT, status TEXT, joined TEXT, expired TEXT, address TEXT, addr_ext TEXT, city TEXT, state TEXT, zip TEXT, mobile TEXT, phone TEXT)",
+		"CREATE TABLE alias (id INTEGER PRIMARY KEY, memberid INTEGER, firstname TEXT, lastname TEXT, email TEXT)",
+	} {
+		_, err := db.Exec(query)
+		if err != nil {
+			t.Fatalf("create table failed: %s", err)
+		}
+	}
+
+	store := &api.FileStore{Dir: filepath.Join(dir, "secret")}
+	err := store.Write(api.CredsSlack, []byte(`{"access_token": "xoxb-test", "admin_token": "xoxp-test"}`))
+	if err != nil {
+		t.Fatalf("write creds failed: %s", err)
+	}
+
+	client := &api.Client{HTTP: srv.Client()}
+	creds := &api.CredsModel{Client: client, Store: store}
+
+	app := &Application{
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+		InfoLog:  log.New(ioutil.Discard, "", 0),
+		Ctx:      context.Background(),
+		Config: &Configuration{
+			Expire: "1963-11-04",
+			Grace:  90,
+			Runs:   3,
+			Wait:   14,
+			Action: "deactivate",
+		},
+		Creds:       creds,
+		MemberSQL:   &sqlite.MemberModel{DB: db},
+		OutreachSQL: &sqlite.OutreachModel{DB: db},
+		RunSQL:      &sqlite.RunModel{DB: db},
+		ActionSQL:   &sqlite.ActionModel{DB: db},
+		SlackMemberAPI: &api.SlackMemberModel{
+			Client:  api.NewSlackClient(client, creds.SlackTokens()),
+			Admin:   api.NewSlackClient(client, creds.SlackAdminTokens()),
+			BaseURL: srv.URL,
+		},
+	}
+
+	for _, err := range []error{app.OutreachSQL.Init(), app.RunSQL.Init(), app.ActionSQL.Init()} {
+		if err != nil {
+			t.Fatalf("init db failed: %s", err)
+		}
+	}
+
+	return app
+}
+
+// Workspace users, member records, earlier actions and snapshots of the policy tests:
+//   - U1 expired, never warned: warn
+//   - U2 expired, warned 30 days ago: guest
+//   - U3 expired, guest 30 days ago: deactivate
+//   - U4 expired, but a workspace admin: no action
+//   - U5 expired, warned 3 days ago: waiting
+//   - U6 not found today, in a snapshot of yesterday and of 2 days ago: warn
+//   - U7 not found today and in two snapshots of yesterday, but found 2 days ago: no action
+//   - U8 deactivated, not found today and in the snapshots of the previous days: no action
+//   - U9 expired, guest before the expiry, i.e. in an earlier lapse: warn
+//   - U10 expired, warned 60 days ago, but matched Active 2 days ago: warn
+func setupPolicy(t *testing.T) (*Application, *slackServer) {
+
+	deleted := slackUser("U8", "Hank", "Gone", false)
+	deleted.Deleted = true
+
+	srv := newSlackServer(t, []models.Member{
+		slackUser("U1", "Alice", "Warn", false),
+		slackUser("U2", "Bob", "Guest", false),
+		slackUser("U3", "Carol", "Deact", false),
+		slackUser("U4", "Dan", "Admin", true),
+		slackUser("U5", "Erin", "Recent", false),
+		slackUser("U6", "Frank", "Nobody", false),
+		slackUser("U7", "Grace", "Nobody", false),
+		deleted,
+		slackUser("U9", "Ivy", "Lapse", false),
+		slackUser("U10", "Jack", "Again", false),
+	})
+	app := newPolicyApp(t, srv)
+
+	for i, name := range []string{"Alice Warn", "Bob Guest", "Carol Deact", "Dan Admin", "Erin Recent", "Ivy Lapse", "Jack Again"} {
+		n := strings.Fields(name)
+		m := &models.MemberSVTC{
+			Num:       fmt.Sprintf("%d001", i+1),
+			Active:    true,
+			FirstName: n[0],
+			LastName:  n[1],
+			Email:     strings.ToLower(n[0]) + "@example.com",
+			Status:    models.StatusExpired,
+			Joined:    "2015-01-01",
+			Expired:   "2020-12-31",
+		}
+		err := app.MemberSQL.Insert(m)
+		if err != nil {
+			t.Fatalf("insert member failed: %s", err)
+		}
+	}
+
+	day := func(days int) string { return time.Now().Local().AddDate(0, 0, -days).Format("2006-01-02") }
+
+	for _, a := range []*models.Action{
+		{Date: day(30), UserID: "U2", Identity: "bob@example.com", Action: "warn", Result: "ok"},
+		{Date: day(30), UserID: "U3", Identity: "carol@example.com", Action: "guest", Result: "ok"},
+		{Date: day(3), UserID: "U5", Identity: "erin@example.com", Action: "warn", Result: "ok"},
+		{Date: "2019-06-01", UserID: "U9", Identity: "ivy@example.com", Action: "guest", Result: "ok"},
+		{Date: day(60), UserID: "U10", Identity: "jack@example.com", Action: "warn", Result: "ok"},
+	} {
+		err := app.ActionSQL.Insert(a)
+		if err != nil {
+			t.Fatalf("insert action failed: %s", err)
+		}
+	}
+
+	nf := func(id string) *models.RunUser { return &models.RunUser{Identity: id, Status: statusNotFound} }
+	for _, r := range []struct {
+		date  string
+		users []*models.RunUser
+	}{
+		{day(2) + " 08:00:00", []*models.RunUser{nf("frank@example.com"), {Identity: "grace@example.com", Num: "9001", Status: "Active"}, nf("hank@example.com"), {Identity: "jack@example.com", Num: "7001", Status: "Active"}}},
+		{day(1) + " 08:00:00", []*models.RunUser{nf("frank@example.com"), nf("grace@example.com"), nf("hank@example.com")}},
+		{day(1) + " 20:00:00", []*models.RunUser{nf("frank@example.com"), nf("grace@example.com"), nf("hank@example.com")}},
+	} {
+		_, err := app.RunSQL.Insert(&models.Run{Date: r.date, Platform: "slack", Total: len(r.users)}, r.users)
+		if err != nil {
+			t.Fatalf("insert run failed: %s", err)
+		}
+	}
+
+	return app, srv
+}
+
+// --------------------------------------------------------------------------------------------
+
+func TestApplyPolicy(t *testing.T) {
+
+	app, srv := setupPolicy(t)
+
+	err := app.ApplyPolicy()
+	if err != nil {
+		t.Fatalf("ApplyPolicy: %s", err)
+	}
+
+	for method, want := range map[string]string{
+		"conversations.open":        "U1,U6,U9,U10",
+		"chat.postMessage":          "U1,U6,U9,U10",
+		"admin.users.setRestricted": "U2",
+		"admin.users.remove":        "U3",
+	} {
+		if got := strings.Join(srv.called(method), ","); got != want {
+			t.Errorf("%s called for %q, expected %q", method, got, want)
+		}
+	}
+
+	al, err := app.ActionSQL.List()
+	if err != nil {
+		t.Fatalf("list actions failed: %s", err)
+	}
+	taken := map[string]string{}
+	for _, a := range al {
+		if a.Date == time.Now().Local().Format("2006-01-02") {
+			taken[a.UserID] = a.Action + " " + a.Result
+		}
+	}
+	for user, want := range map[string]string{"U1": "warn ok", "U2": "guest ok", "U3": "deactivate ok", "U6": "warn ok", "U9": "warn ok", "U10": "warn ok"} {
+		if taken[user] != want {
+			t.Errorf("action of %s is %q, expected %q", user, taken[user], want)
+		}
+	}
+	if len(taken) != 6 {
+		t.Errorf("actions taken for %v, expected U1, U2, U3, U6, U9 and U10 only", taken)
+	}
+
+	rl, err := app.RunSQL.List("slack", 0)
+	if err != nil {
+		t.Fatalf("list runs failed: %s", err)
+	}
+	if len(rl) != 4 {
+		t.Errorf("%d snapshots, expected the snapshot of the policy run to be stored", len(rl))
+	}
+}
+
+func TestApplyPolicyPreview(t *testing.T) {
+
+	app, srv := setupPolicy(t)
+	app.Config.Preview = true
+
+	err := app.ApplyPolicy()
+	if err != nil {
+		t.Fatalf("ApplyPolicy: %s", err)
+	}
+
+	srv.mu.Lock()
+	calls := len(srv.calls)
+	srv.mu.Unlock()
+	if calls != 0 {
+		t.Errorf("Slack api methods called in preview: %v", srv.calls)
+	}
+
+	al, err := app.ActionSQL.List()
+	if err != nil {
+		t.Fatalf("list actions failed: %s", err)
+	}
+	if len(al) != 5 {
+		t.Errorf("%d actions logged in preview, expected the 5 earlier ones", len(al))
+	}
+
+	rl, err := app.RunSQL.List("slack", 0)
+	if err != nil {
+		t.Fatalf("list runs failed: %s", err)
+	}
+	if len(rl) != 3 {
+		t.Errorf("%d snapshots, expected no snapshot to be stored in preview", len(rl))
+	}
+}
@@ -0,0 +1,378 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"svtc-sync/pkg/models"
+)
+
+// Geometry of the inline SVG bar charts of the HTML report
+const (
+	chartHeight = 220 // Height of a chart incl. axis labels
+	chartPlot   = 170 // Height of the plot area, i.e. of the largest bar
+	chartTop    = 20  // Space above the plot area for values
+	chartGroup  = 48  // Width of a group of bars, i.e. a status or month
+)
+
+// Page of the HTML report. Styles and the script to sort tables are inline, so that the file is self-contained.
+const reportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>SVTC Membership Report {{.Stats.Date}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; } h2 { font-size: 1.25em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; margin: 0.5em 0; font-size: 0.9em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f3f3f3; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; } th.desc::after { content: " \25BC"; }
+td.n { text-align: right; }
+svg { display: block; margin: 0.5em 0; font-size: 11px; }
+svg .a { fill: #2b7bb9; } svg .b { fill: #e0843a; } svg text { fill: #444; }
+.legend span { display: inline-block; width: 0.8em; height: 0.8em; margin: 0 0.3em 0 1em; }
+.note { color: #666; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>SVTC Membership Report</h1>
+<p class="note">Generated {{.Generated}} by svtc-sync{{if not .PII}}, member emails masked{{end}}.</p>
+
+<h2>Members</h2>
+<p>{{.Stats.Total}} member records.</p>
+{{template "chart" .StatusChart}}
+<table class="sortable">
+<thead><tr><th>Status</th><th>Members</th></tr></thead>
+<tbody>{{range .StatusRows}}<tr><td>{{.Name}}</td><td class="n">{{.Count}}</td></tr>{{end}}</tbody>
+</table>
+
+<h2>Joins and Expirations</h2>
+<div class="legend"><span style="background:#2b7bb9"></span>joined<span style="background:#e0843a"></span>expired</div>
+{{template "chart" .MonthChart}}
+<table class="sortable">
+<thead><tr><th>Month</th><th>Joined</th><th>Expired</th></tr></thead>
+<tbody>{{range .Stats.Months}}<tr><td>{{.Month}}</td><td class="n">{{.Joined}}</td><td class="n">{{.Expired}}</td></tr>{{end}}</tbody>
+</table>
+
+<h2>Renewal and Trial Conversion</h2>
+<table class="sortable">
+<thead><tr><th>Year</th><th>Members</th><th>Renewed</th><th>Rate %</th></tr></thead>
+<tbody>{{range .Stats.Renewal}}<tr><td>{{.Year}}</td><td class="n">{{.Members}}</td><td class="n">{{.Renewed}}</td><td class="n">{{printf "%.1f" .Rate}}</td></tr>{{end}}</tbody>
+</table>
+<p>{{.Stats.Trials.Converted}} of {{.Stats.Trials.Ended}} ended trials converted ({{printf "%.1f" .Stats.Trials.Rate}}%), {{.Stats.Trials.Current}} members on trial.</p>
+
+<h2>Platform Presence</h2>
+<p class="note">Based on the most recent check runs:{{range .Runs}} {{.Platform}} {{.Date}} ({{.Total}} users);{{else}} none stored.{{end}}</p>
+<table>
+<thead><tr><th>Current members</th><th>Slack</th><th>No Slack</th></tr></thead>
+<tbody>
+<tr><th>Strava</th><td class="n">{{.Presence.Both}}</td><td class="n">{{.Presence.Strava}}</td></tr>
+<tr><th>No Strava</th><td class="n">{{.Presence.Slack}}</td><td class="n">{{.Presence.None}}</td></tr>
+</tbody>
+</table>
+<table class="sortable">
+<thead><tr><th>Num</th><th>Name</th><th>Email</th><th>Status</th><th>Expired</th><th>Slack</th><th>Strava</th></tr></thead>
+<tbody>{{range .Members}}<tr><td>{{.Num}}</td><td>{{.Name}}</td><td>{{.Email}}</td><td>{{.Status}}</td><td>{{.Expired}}</td><td>{{if .Slack}}&#10003;{{end}}</td><td>{{if .Strava}}&#10003;{{end}}</td></tr>{{end}}</tbody>
+</table>
+
+<h2>Not Found</h2>
+<p>Platform users that could not be matched to a member record.</p>
+<table class="sortable">
+<thead><tr><th>Platform</th><th>Name</th><th>Identity</th></tr></thead>
+<tbody>{{range .NotFound}}<tr><td>{{.Platform}}</td><td>{{.Name}}</td><td>{{.Identity}}</td></tr>{{end}}</tbody>
+</table>
+
+<h2>Expired</h2>
+<p>Platform users matched to a member with status Expired.</p>
+<table class="sortable">
+<thead><tr><th>Platform</th><th>Name</th><th>Num</th><th>Identity</th><th>Expired</th></tr></thead>
+<tbody>{{range .Expired}}<tr><td>{{.Platform}}</td><td>{{.Name}}</td><td>{{.Num}}</td><td>{{.Identity}}</td><td>{{.Expired}}</td></tr>{{end}}</tbody>
+</table>
+
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table"), body = table.tBodies[0], col = th.cellIndex;
+    var asc = !th.classList.contains("asc");
+    table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[col].textContent.trim(), y = b.cells[col].textContent.trim();
+      var c = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y);
+      return asc ? c : -c;
+    });
+    rows.forEach(function (r) { body.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+{{define "chart"}}<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="{{.Title}}">
+{{range .Bars}}<rect class="{{.Class}}" x="{{.X}}" y="{{.Y}}" width="{{.W}}" height="{{.H}}"><title>{{.Label}}: {{.Value}}</title></rect><text x="{{.TextX}}" y="{{.TextY}}" text-anchor="middle">{{.Value}}</text>
+{{end}}{{range .Labels}}<text x="{{.X}}" y="{{.Y}}" text-anchor="middle">{{.Text}}</text>
+{{end}}</svg>{{end}}
+`
+
+// --------------------------------------------------------------------------------------------
+
+// Data of the HTML report
+type report struct {
+	Generated   string
+	PII         bool
+	Stats       *Stats
+	StatusRows  []*statusRow
+	StatusChart *chart
+	MonthChart  *chart
+	Runs        []*models.Run
+	Presence    presence
+	Members     []*presenceRow
+	NotFound    []*reportUser
+	Expired     []*reportUser
+}
+
+type statusRow struct {
+	Name  string
+	Count int
+}
+
+// Platform user of a check run, Not Found or matched to an Expired member
+type reportUser struct {
+	Platform, Name, Identity, Num, Expired string
+}
+
+// Number of current members (Active or Trial) by platform presence
+type presence struct {
+	Both, Slack, Strava, None int
+}
+
+// Member of the presence table, i.e. a current member or a member present on a platform
+type presenceRow struct {
+	Num, Name, Email, Status, Expired string
+	Slack, Strava                     bool
+}
+
+// Inline SVG bar chart
+type chart struct {
+	Title         string
+	Width, Height int
+	Bars          []*bar
+	Labels        []*label
+}
+
+type bar struct {
+	X, Y, W, H   int
+	TextX, TextY int
+	Class, Label string
+	Value        int
+}
+
+type label struct {
+	X, Y int
+	Text string
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Render the statistics, the platform presence of members, and the platform users Not Found or Expired in the
+// most recent check runs into a self-contained HTML file. Member emails are masked unless the include-pii
+// option is set.
+func (app *Application) ReportHTML(path string) error {
+
+	now := time.Now().Local()
+
+	st, err := app.stats(now)
+	if err != nil {
+		return err
+	}
+
+	ml, err := app.MemberSQL.ListMembers()
+	if err != nil {
+		app.ErrorLog.Printf("[ListMembers SQL] %s", err)
+		return err
+	}
+
+	r := &report{
+		Generated: now.Format("2006-01-02 15:04"),
+		PII:       app.Config.PII,
+		Stats:     st,
+		NotFound:  []*reportUser{},
+		Expired:   []*reportUser{},
+	}
+
+	// Users of the most recent run of each platform, by matched member number
+	present := map[string]map[string]bool{}
+	for _, platform := range []string{"slack", "strava"} {
+
+		present[platform] = map[string]bool{}
+
+		rl, err := app.RunSQL.List(platform, 1)
+		if err != nil {
+			app.ErrorLog.Printf("[Run SQL] %s", err)
+			return err
+		}
+		if len(rl) == 0 {
+			continue
+		}
+		r.Runs = append(r.Runs, rl[0])
+
+		ul, err := app.RunSQL.Users(rl[0].ID)
+		if err != nil {
+			app.ErrorLog.Printf("[Run SQL] %s", err)
+			return err
+		}
+
+		for _, u := range ul {
+			if u.Num != "" {
+				present[platform][u.Num] = true
+			}
+			ru := &reportUser{Platform: platform, Name: u.Name, Identity: u.Identity, Num: u.Num, Expired: u.Expired}
+			if !app.Config.PII {
+				ru.Identity = maskEmail(ru.Identity)
+			}
+			switch u.Status {
+			case statusNotFound:
+				r.NotFound = append(r.NotFound, ru)
+			case string(models.StatusExpired):
+				r.Expired = append(r.Expired, ru)
+			}
+		}
+	}
+
+	for _, m := range ml {
+
+		current := m.Status == models.StatusActive || m.Status == models.StatusTrial
+		slack, strava := present["slack"][m.Num], present["strava"][m.Num]
+
+		if current {
+			switch {
+			case slack && strava:
+				r.Presence.Both++
+			case slack:
+				r.Presence.Slack++
+			case strava:
+				r.Presence.Strava++
+			default:
+				r.Presence.None++
+			}
+		}
+		if !current && !slack && !strava {
+			continue
+		}
+
+		email := m.Email
+		if !app.Config.PII {
+			email = maskEmail(email)
+		}
+		r.Members = append(r.Members, &presenceRow{
+			Num:     m.Num,
+			Name:    strings.TrimSpace(m.FirstName + " " + m.LastName),
+			Email:   email,
+			Status:  string(m.Status),
+			Expired: m.Expired,
+			Slack:   slack,
+			Strava:  strava,
+		})
+	}
+	sort.Slice(r.Members, func(i, j int) bool { return r.Members[i].Name < r.Members[j].Name })
+
+	// Charts of the members by status, and of joins and expirations by month
+	keys := sortedKeys(st.Status)
+	counts := []int{}
+	for _, k := range keys {
+		r.StatusRows = append(r.StatusRows, &statusRow{Name: k, Count: st.Status[k]})
+		counts = append(counts, st.Status[k])
+	}
+	r.StatusChart = barChart("Members by status", keys, []string{"a"}, counts)
+
+	months, joined, expired := []string{}, []int{}, []int{}
+	for _, ms := range st.Months {
+		months = append(months, ms.Month)
+		joined = append(joined, ms.Joined)
+		expired = append(expired, ms.Expired)
+	}
+	r.MonthChart = barChart("Joins and expirations by month", months, []string{"a", "b"}, joined, expired)
+
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return fmt.Errorf("parse of report template failed: %w", err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, r)
+	if err != nil {
+		return fmt.Errorf("execution of report template failed: %w", err)
+	}
+
+	if path == "" {
+		path = fmt.Sprintf("svtc-report-%s.html", now.Format("2006-01-02"))
+	}
+
+	// The report holds member data, it is readable by the owner only
+	err = ioutil.WriteFile(path, buf.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+
+	app.InfoLog.Printf("[ReportHTML] Wrote report of %d members to %s \n", st.Total, path)
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Build a bar chart with a group of bars per label, i.e. one bar of each series with the given classes
+func barChart(title string, labels []string, classes []string, series ...[]int) *chart {
+
+	max := 1
+	for _, s := range series {
+		for _, v := range s {
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	c := &chart{
+		Title:  title,
+		Width:  len(labels)*chartGroup + 20,
+		Height: chartHeight,
+	}
+
+	w := (chartGroup - 8) / len(series)
+	base := chartTop + chartPlot
+
+	for i, l := range labels {
+		x := 10 + i*chartGroup + 4
+		for j, s := range series {
+			h := s[i] * chartPlot / max
+			c.Bars = append(c.Bars, &bar{
+				X: x + j*w, Y: base - h, W: w - 2, H: h,
+				TextX: x + j*w + (w-2)/2, TextY: base - h - 4,
+				Class: classes[j], Label: l, Value: s[i],
+			})
+		}
+		c.Labels = append(c.Labels, &label{X: x + (chartGroup-8)/2, Y: base + 16, Text: l})
+	}
+
+	return c
+}
+
+// Mask the local part of an email address, e.g. j***@example.com, other identities are returned as is
+func maskEmail(email string) string {
+
+	i := strings.LastIndex(email, "@")
+	if i < 1 {
+		return email
+	}
+
+	return email[:1] + "***" + email[i:]
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Search member records with a filter expression, e.g. status in (Expired, Trial) and expired >= 2023-01-01,
+// given as one or more arguments, sorted and limited by the sort and limit options. Records are output like the
+// ref command, in RFC 5322 format with the email option, or in the format of the format option, i.e. json or csv
+// with all columns.
+func (app *Application) Search(args []string) error {
+
+	ml, err := app.MemberSQL.Search(strings.Join(args, " "), app.Config.Sort, app.Config.Limit)
+	if err != nil {
+		app.ErrorLog.Printf("[Search SQL] %s", err)
+		return err
+	}
+
+	if app.Config.Email {
+		for _, m := range ml {
+			if m.Email != "" {
+				fmt.Printf("%s %s <%s>,\n", m.FirstName, m.LastName, m.Email)
+			}
+		}
+		return nil
+	}
+
+	switch app.Config.Format {
+
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(ml)
+		if err != nil {
+			return fmt.Errorf("json encoding of members failed: %w", err)
+		}
+
+	case "csv":
+		rows := [][]string{{"num", "active", "login", "firstname", "middle", "lastname", "email", "status", "joined", "expired",
+			"address", "addr_ext", "city", "state", "zip", "mobile", "phone"}}
+		for _, m := range ml {
+			active := "0"
+			if m.Active {
+				active = "1"
+			}
+			rows = append(rows, []string{m.Num, active, m.Login, m.FirstName, m.Middle, m.LastName,
+				m.Email, string(m.Status), m.Joined, m.Expired, m.Address, m.AddrExt, m.City, m.State, m.Zip, m.Mobile, m.Phone})
+		}
+		err = csv.NewWriter(os.Stdout).WriteAll(rows)
+		if err != nil {
+			return fmt.Errorf("write csv failed: %w", err)
+		}
+
+	default:
+		for _, m := range ml {
+			fmt.Printf("%s %s %s %s %s %s \n", m.Num, m.FirstName, m.LastName, m.Email, m.Status, m.Expired)
+		}
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,277 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"svtc-sync/pkg/models"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Render the template for each member matched on the specified platform and deliver the messages via the
+// configured SMTP relay. Members on the do-not-contact list and members that have already been sent the same
+// template (within the recent window, or today if not set) are skipped, so that a run can be resumed after a
+// partial failure. Each successful delivery is recorded as an outreach record. In preview mode messages are
+// rendered, but neither sent nor recorded. Requires an output filter of EXP or TRI, so that only members with
+// an expired membership or a trial are sent reminders.
+func (app *Application) Send(platform string) error {
+
+	if app.Config.Output != "EXP" && app.Config.Output != "TRI" {
+		return fmt.Errorf("send requires an output filter of EXP or TRI")
+	}
+
+	mtl, err := app.matchPlatform(platform)
+	if err != nil {
+		return err
+	}
+
+	return app.deliver(recipients(mtl))
+}
+
+// Deliver the rendered template to the members via the SMTP relay, skipping members on the do-not-contact list
+// and members that have been sent the template already
+func (app *Application) deliver(ml []*models.MemberSVTC) error {
+
+	from, err := app.fromAddress()
+	if err != nil {
+		return err
+	}
+
+	creds, err := app.smtpCreds()
+	if err != nil {
+		app.ErrorLog.Printf("[smtpCreds] %s", err)
+		return err
+	}
+
+	t, name, err := app.template(defaultTemplateName, defaultTemplate)
+	if err != nil {
+		app.ErrorLog.Printf("[template] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[Send] Using template %s \n", name)
+
+	// Get do-not-contact list and members that have been sent this template already
+	dnc, err := app.DoNotContactSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[DNC SQL] %s", err)
+		return err
+	}
+	blocked := map[string]bool{}
+	for _, d := range dnc {
+		blocked[d.Email] = true
+	}
+
+	sent, err := app.sentTemplate(name)
+	if err != nil {
+		app.ErrorLog.Printf("[sentTemplate] %s", err)
+		return err
+	}
+
+	if app.Config.Preview {
+		app.InfoLog.Printf("[Send] Preview flag set: NOT sending messages \n")
+	}
+	app.InfoLog.Printf("[Send] Sending messages to up to %d unique member email addresses at %d per minute \n\n", len(ml), app.Config.Rate)
+
+	// Space out deliveries according to the configured rate
+	interval := time.Minute / time.Duration(app.Config.Rate)
+	var last time.Time
+
+	var nSent, nSkipped, nFailed int
+
+	for _, m := range ml {
+
+		email := strings.ToLower(strings.TrimSpace(m.Email))
+
+		if blocked[email] {
+			fmt.Printf("[%s] %s %s <%s> - skipped (do not contact) \n", m.Num, m.FirstName, m.LastName, m.Email)
+			nSkipped++
+			continue
+		}
+
+		if d, ok := sent[m.Num]; ok {
+			fmt.Printf("[%s] %s %s <%s> - skipped (sent %s) \n", m.Num, m.FirstName, m.LastName, m.Email, d)
+			nSkipped++
+			continue
+		}
+
+		msg, err := render(t, m)
+		if err != nil {
+			app.ErrorLog.Printf("[render] %s: %s", m.Num, err)
+			return err
+		}
+
+		if app.Config.Preview {
+			fmt.Printf("[%s] %s - %s \n", m.Num, msg.To, msg.Subject)
+			continue
+		}
+
+		if wait := interval - time.Since(last); wait > 0 {
+			err = app.sleep(wait)
+			if err != nil {
+				return err
+			}
+		}
+		last = time.Now()
+
+		err = app.MailAPI.Send(creds, from.Address, m.Email, msg.bytes(from.String()))
+		if err != nil {
+			fmt.Printf("[%s] %s %s <%s> - failed \n", m.Num, m.FirstName, m.LastName, m.Email)
+			app.ErrorLog.Printf("[Send] %s", err)
+			nFailed++
+			continue
+		}
+		fmt.Printf("[%s] %s %s <%s> - sent \n", m.Num, m.FirstName, m.LastName, m.Email)
+		nSent++
+
+		o := &models.Outreach{
+			Num:      m.Num,
+			Channel:  "email",
+			Date:     time.Now().Local().Format("2006-01-02"),
+			Template: name,
+			Notes:    "sent via smtp",
+		}
+		err = app.OutreachSQL.Insert(o)
+		if err != nil {
+			app.ErrorLog.Printf("[Insert] %s", err)
+			return err
+		}
+
+	}
+
+	app.InfoLog.Printf("[Send] Sent %d, skipped %d, failed %d messages \n", nSent, nSkipped, nFailed)
+
+	if nFailed > 0 {
+		return fmt.Errorf("%d messages failed, re-run to resume", nFailed)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Return the SMTP relay credentials from file, or a relay without authentication if a host:port is
+// specified via the smtp option (e.g. a local MailHog instance). Not required in preview mode.
+func (app *Application) smtpCreds() (*models.SMTPCreds, error) {
+
+	if app.Config.SMTP != "" {
+
+		host, port, err := net.SplitHostPort(app.Config.SMTP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP relay %s: %w", app.Config.SMTP, err)
+		}
+
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP relay port %s: %w", port, err)
+		}
+
+		return &models.SMTPCreds{Host: host, Port: p}, nil
+	}
+
+	if app.Config.Preview {
+		return nil, nil
+	}
+
+	creds, err := app.Creds.ReadSMTPCreds()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read SMTP credentials %w", err)
+	}
+
+	return creds, nil
+}
+
+// Return the members that have been sent the specified template via email, within the recent window or
+// today if not set, mapped to the date sent.
+func (app *Application) sentTemplate(name string) (map[string]string, error) {
+
+	since := time.Now().Local().AddDate(0, 0, -app.Config.Recent).Format("2006-01-02")
+
+	ol, err := app.OutreachSQL.List(since)
+	if err != nil {
+		return nil, err
+	}
+
+	sent := map[string]string{}
+	for _, o := range ol {
+		if o.Num != "" && o.Channel == "email" && o.Template == name {
+			if _, ok := sent[o.Num]; !ok {
+				sent[o.Num] = o.Date
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (app *Application) ListDoNotContact() error {
+
+	dl, err := app.DoNotContactSQL.List()
+	if err != nil {
+		app.ErrorLog.Printf("[DNC SQL] %s", err)
+		return err
+	}
+
+	for _, d := range dl {
+		fmt.Printf("%s %s %s \n", d.Email, d.Date, d.Reason)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Add or remove an email address to/from the do-not-contact list. Arguments are expected as
+//
+//	(add|remove) <email>
+//
+// with the reason taken from the notes option.
+func (app *Application) EditDoNotContact(args []string) error {
+
+	if len(args) != 2 {
+		return fmt.Errorf("invalid dnc arguments: %s", strings.Join(args, " "))
+	}
+
+	switch args[0] {
+
+	case "add":
+
+		d := &models.DoNotContact{
+			Email:  args[1],
+			Date:   time.Now().Local().Format("2006-01-02"),
+			Reason: app.Config.Notes,
+		}
+
+		err := app.DoNotContactSQL.Insert(d)
+		if err != nil {
+			app.ErrorLog.Printf("[Insert] %s", err)
+			return err
+		}
+		app.InfoLog.Printf("[EditDoNotContact] Added %s to do-not-contact list", args[1])
+
+	case "remove":
+
+		err := app.DoNotContactSQL.Delete(args[1])
+		if err != nil {
+			app.ErrorLog.Printf("[Delete] %s", err)
+			return err
+		}
+		app.InfoLog.Printf("[EditDoNotContact] Removed %s from do-not-contact list", args[1])
+
+	default:
+		return fmt.Errorf("invalid dnc arguments: %s", strings.Join(args, " "))
+
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
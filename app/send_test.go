@@ -0,0 +1,231 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"svtc-sync/pkg/models"
+	"svtc-sync/pkg/models/api"
+	"svtc-sync/pkg/models/sqlite"
+)
+
+// Minimal SMTP server that accepts messages, except for the recipients in reject, and records the recipients
+// of the messages delivered
+type smtpServer struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	reject map[string]bool
+	rcpts  []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %s", err)
+	}
+	s := &smtpServer{ln: ln, reject: map[string]bool{}}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	var rcpt string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			rcpt = strings.ToLower(strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Lock()
+			rejected := s.reject[rcpt]
+			s.mu.Unlock()
+			if rejected {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, rcpt)
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) delivered() []string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.rcpts...)
+}
+
+// Open a new DB in a temporary directory, that is removed along with the DB when the test completes
+func newTestDB(t *testing.T) (*sql.DB, string) {
+
+	dir, err := ioutil.TempDir("", "svtc-sync")
+	if err != nil {
+		t.Fatalf("temp dir failed: %s", err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db failed: %s", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	return db, dir
+}
+
+func newSendApp(t *testing.T, relay string) *Application {
+
+	db, _ := newTestDB(t)
+
+	app := &Application{
+		ErrorLog:        log.New(ioutil.Discard, "", 0),
+		InfoLog:         log.New(ioutil.Discard, "", 0),
+		Ctx:             context.Background(),
+		Config:          &Configuration{From: "SVTC <membership@svtc.org>", SMTP: relay, Rate: 60000},
+		OutreachSQL:     &sqlite.OutreachModel{DB: db},
+		DoNotContactSQL: &sqlite.DoNotContactModel{DB: db},
+		MailAPI:         &api.MailModel{},
+	}
+
+	for _, err := range []error{app.OutreachSQL.Init(), app.DoNotContactSQL.Init()} {
+		if err != nil {
+			t.Fatalf("init db failed: %s", err)
+		}
+	}
+
+	return app
+}
+
+func sendMembers() []*models.MemberSVTC {
+
+	return []*models.MemberSVTC{
+		{Num: "1001", FirstName: "Dave", LastName: "Scott", Email: "dave@example.com", Status: models.StatusExpired, Expired: "2021-06-30"},
+		{Num: "1002", FirstName: "Mark", LastName: "Allen", Email: "mark@example.com", Status: models.StatusExpired, Expired: "2021-07-31"},
+		{Num: "1003", FirstName: "Paula", LastName: "Newby", Email: "paula@example.com", Status: models.StatusExpired, Expired: "2021-08-31"},
+	}
+}
+
+// --------------------------------------------------------------------------------------------
+
+func TestSendRequiresOutputFilter(t *testing.T) {
+
+	app := newSendApp(t, "127.0.0.1:25")
+
+	for _, out := range []string{"", "ACT", "all"} {
+		app.Config.Output = out
+		err := app.Send("slack")
+		if err == nil || !strings.Contains(err.Error(), "EXP or TRI") {
+			t.Errorf("Send with -out %q: expected output filter error, got %v", out, err)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+
+	srv := newSMTPServer(t)
+	app := newSendApp(t, srv.ln.Addr().String())
+
+	err := app.DoNotContactSQL.Insert(&models.DoNotContact{Email: "paula@example.com", Date: "2021-09-01", Reason: "unsubscribed"})
+	if err != nil {
+		t.Fatalf("insert dnc failed: %s", err)
+	}
+
+	// First run: the do-not-contact address is skipped, a rejected recipient fails
+	srv.reject["mark@example.com"] = true
+
+	err = app.deliver(sendMembers())
+	if err == nil || !strings.Contains(err.Error(), "re-run to resume") {
+		t.Fatalf("first run: expected resume error, got %v", err)
+	}
+	if got := srv.delivered(); len(got) != 1 || got[0] != "dave@example.com" {
+		t.Fatalf("first run: delivered %v, expected [dave@example.com]", got)
+	}
+
+	sent, err := app.sentTemplate(defaultTemplateName)
+	if err != nil {
+		t.Fatalf("sentTemplate failed: %s", err)
+	}
+	if _, ok := sent["1001"]; !ok || len(sent) != 1 {
+		t.Fatalf("first run: outreach records for %v, expected 1001 only", sent)
+	}
+
+	// Re-run: members sent already are skipped, the failed one is delivered
+	srv.mu.Lock()
+	delete(srv.reject, "mark@example.com")
+	srv.mu.Unlock()
+
+	err = app.deliver(sendMembers())
+	if err != nil {
+		t.Fatalf("resume: %s", err)
+	}
+	if got := srv.delivered(); len(got) != 2 || got[1] != "mark@example.com" {
+		t.Fatalf("resume: delivered %v, expected [dave@example.com mark@example.com]", got)
+	}
+
+	sent, err = app.sentTemplate(defaultTemplateName)
+	if err != nil {
+		t.Fatalf("sentTemplate failed: %s", err)
+	}
+	if _, ok := sent["1003"]; ok || len(sent) != 2 {
+		t.Fatalf("resume: outreach records for %v, expected 1001 and 1002", sent)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Maximum size of a Slack request body that is accepted
+const maxRequestBody = 1 << 20
+
+// Maximum age of a Slack request timestamp, to protect against replay attacks
+const maxRequestAge = 5 * time.Minute
+
+// --------------------------------------------------------------------------------------------
+
+// Start an http server on the configured address to receive requests from Slack. All requests are verified
+// with the Slack app signing secret.
+func (app *Application) Serve() error {
+
+	creds, err := app.Creds.ReadSlackBotCreds()
+	if err != nil {
+		app.ErrorLog.Printf("[ReadSlackBotCreds] Unable to read Slack bot credentials %s", err)
+		return err
+	}
+	if creds.Signing_Secret == "" {
+		return fmt.Errorf("no Slack signing secret configured")
+	}
+
+	srv := &http.Server{
+		Addr:         app.Config.Addr,
+		ErrorLog:     app.ErrorLog,
+		Handler:      app.routes(creds.Signing_Secret),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	// Stop accepting requests on Ctrl-C, and let requests in progress complete
+	go func() {
+		<-app.Ctx.Done()
+		app.InfoLog.Printf("[Serve] Shutting down server")
+		srv.Shutdown(context.Background())
+	}()
+
+	app.InfoLog.Printf("[Serve] Starting server on %s", app.Config.Addr)
+
+	err = srv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (app *Application) routes(secret string) http.Handler {
+
+	mux := http.NewServeMux()
+	mux.Handle("/slack/events", app.verifySlack(secret, http.HandlerFunc(app.slackEvents)))
+	mux.Handle("/slack/commands", app.verifySlack(secret, http.HandlerFunc(app.slackCommand)))
+
+	return mux
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Middleware to verify the signature of Slack requests, i.e. the X-Slack-Signature header holds the HMAC
+// SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret. The body is restored for the next handler.
+func (app *Application) verifySlack(secret string, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		ts := r.Header.Get("X-Slack-Request-Timestamp")
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		age := time.Since(time.Unix(sec, 0))
+		if err != nil || age > maxRequestAge || age < -maxRequestAge {
+			app.ErrorLog.Printf("[verifySlack] Rejected request with invalid timestamp from %s", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + ts + ":"))
+		mac.Write(body)
+		expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature"))) {
+			app.ErrorLog.Printf("[verifySlack] Rejected request with invalid signature from %s", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,333 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Number of months of joins and expirations, and of years of renewal rates, reported by stats
+const (
+	statsMonths = 12
+	statsYears  = 5
+	statsRuns   = 6 // Number of most recent runs per platform
+)
+
+// Membership statistics, derived from the joined and expired dates of the member records and the stored
+// snapshots of platform check runs
+type Stats struct {
+	Date      string          `json:"date"`
+	Total     int             `json:"total"`
+	Status    map[string]int  `json:"status"`
+	Months    []*MonthStats   `json:"months"`
+	Renewal   []*RenewalStats `json:"renewal"`
+	Trials    *TrialStats     `json:"trials"`
+	Platforms []*RunStats     `json:"platforms"`
+}
+
+// New joins and expirations of a month (YYYY-MM)
+type MonthStats struct {
+	Month   string `json:"month"`
+	Joined  int    `json:"joined"`
+	Expired int    `json:"expired"`
+}
+
+// Members at the start of the previous year that were still members at the start of the year
+type RenewalStats struct {
+	Year    int     `json:"year"`
+	Members int     `json:"members"`
+	Renewed int     `json:"renewed"`
+	Rate    float64 `json:"rate"`
+}
+
+// Members seen on a trial membership, either currently or in a run snapshot, and those that converted to a
+// regular membership once the trial ended
+type TrialStats struct {
+	Trials    int     `json:"trials"`
+	Current   int     `json:"current"`
+	Ended     int     `json:"ended"`
+	Converted int     `json:"converted"`
+	Rate      float64 `json:"rate"`
+}
+
+// Share of the users of a platform that are members (Active or Trial) in a check run
+type RunStats struct {
+	Platform string  `json:"platform"`
+	Date     string  `json:"date"`
+	Users    int     `json:"users"`
+	Matched  int     `json:"matched"`
+	Members  int     `json:"members"`
+	Share    float64 `json:"share"`
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Report membership statistics in the format of the format option, i.e. text, json or csv
+func (app *Application) Stats() error {
+
+	st, err := app.stats(time.Now().Local())
+	if err != nil {
+		return err
+	}
+
+	switch app.Config.Format {
+
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(st)
+		if err != nil {
+			return fmt.Errorf("json encoding of stats failed: %w", err)
+		}
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		err = w.WriteAll(st.Rows())
+		if err != nil {
+			return fmt.Errorf("write csv failed: %w", err)
+		}
+
+	default:
+		fmt.Print(st.Text())
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Compute the statistics as of the given date
+func (app *Application) stats(now time.Time) (*Stats, error) {
+
+	ml, err := app.MemberSQL.ListMembers()
+	if err != nil {
+		app.ErrorLog.Printf("[ListMembers SQL] %s", err)
+		return nil, err
+	}
+
+	st := &Stats{
+		Date:   now.Format("2006-01-02"),
+		Total:  len(ml),
+		Status: map[string]int{},
+	}
+
+	members := map[string]*models.MemberSVTC{}
+	for _, m := range ml {
+		st.Status[string(m.Status)]++
+		members[m.Num] = m
+	}
+
+	// Joins and expirations of the last months, most recent last
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	index := map[string]*MonthStats{}
+	for i := statsMonths - 1; i >= 0; i-- {
+		ms := &MonthStats{Month: month.AddDate(0, -i, 0).Format("2006-01")}
+		st.Months = append(st.Months, ms)
+		index[ms.Month] = ms
+	}
+	for _, m := range ml {
+		if ms := index[monthOf(m.Joined)]; ms != nil {
+			ms.Joined++
+		}
+		if ms := index[monthOf(m.Expired)]; ms != nil && m.Expired <= st.Date {
+			ms.Expired++
+		}
+	}
+
+	// Renewal rate of the last years, i.e. members at the start of the previous year that are still members at
+	// the start of the year. As the DB holds the latest expired date only, a membership that lapsed and was
+	// renewed later counts as renewed.
+	for y := now.Year() - statsYears + 1; y <= now.Year(); y++ {
+		rs := &RenewalStats{Year: y}
+		prev, start := fmt.Sprintf("%d-01-01", y-1), fmt.Sprintf("%d-01-01", y)
+		for _, m := range ml {
+			if memberOn(m, prev) {
+				rs.Members++
+				if memberOn(m, start) {
+					rs.Renewed++
+				}
+			}
+		}
+		rs.Rate = rate(rs.Renewed, rs.Members)
+		st.Renewal = append(st.Renewal, rs)
+	}
+
+	// Trial conversion of members currently on trial or seen on trial in a run snapshot
+	nl, err := app.RunSQL.Nums(string(models.StatusTrial))
+	if err != nil {
+		app.ErrorLog.Printf("[Run SQL] %s", err)
+		return nil, err
+	}
+	trials := map[string]bool{}
+	for _, n := range nl {
+		trials[n] = true
+	}
+	for _, m := range ml {
+		if m.Status == models.StatusTrial {
+			trials[m.Num] = true
+		}
+	}
+
+	st.Trials = &TrialStats{Trials: len(trials)}
+	for n := range trials {
+		m := members[n]
+		switch {
+		case m == nil:
+			st.Trials.Ended++
+		case m.Status == models.StatusTrial:
+			st.Trials.Current++
+		case m.Status == models.StatusActive || m.Status == models.StatusFrozen:
+			st.Trials.Ended++
+			st.Trials.Converted++
+		default:
+			st.Trials.Ended++
+		}
+	}
+	st.Trials.Rate = rate(st.Trials.Converted, st.Trials.Ended)
+
+	// Share of platform users that are members in the most recent runs
+	for _, platform := range []string{"slack", "strava"} {
+
+		rl, err := app.RunSQL.List(platform, statsRuns)
+		if err != nil {
+			app.ErrorLog.Printf("[Run SQL] %s", err)
+			return nil, err
+		}
+
+		for _, run := range rl {
+
+			ul, err := app.RunSQL.Users(run.ID)
+			if err != nil {
+				app.ErrorLog.Printf("[Run SQL] %s", err)
+				return nil, err
+			}
+
+			rs := &RunStats{Platform: platform, Date: run.Date, Users: len(ul)}
+			for _, u := range ul {
+				if u.Status == statusNotFound {
+					continue
+				}
+				rs.Matched++
+				if u.Status == string(models.StatusActive) || u.Status == string(models.StatusTrial) {
+					rs.Members++
+				}
+			}
+			rs.Share = rate(rs.Members, rs.Users)
+
+			st.Platforms = append(st.Platforms, rs)
+		}
+	}
+
+	return st, nil
+}
+
+// Month (YYYY-MM) of a date, empty if not a valid date
+func monthOf(date string) string {
+
+	if helpers.GetDate(date).IsZero() {
+		return ""
+	}
+
+	return date[:7]
+}
+
+// A member record covers the date (YYYY-MM-DD), i.e. joined on or before and expires on or after it
+func memberOn(m *models.MemberSVTC, date string) bool {
+
+	if helpers.GetDate(m.Joined).IsZero() || helpers.GetDate(m.Expired).IsZero() {
+		return false
+	}
+
+	return m.Joined <= date && m.Expired >= date
+}
+
+// Percentage of n in total, rounded to one decimal
+func rate(n int, total int) float64 {
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(int(1000*float64(n)/float64(total)+0.5)) / 10
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Format the statistics as text
+func (st *Stats) Text() string {
+
+	s := fmt.Sprintf("Membership statistics as of %s\n\n", st.Date)
+
+	s += fmt.Sprintf("Members by status (%d total)\n", st.Total)
+	for _, k := range sortedKeys(st.Status) {
+		s += fmt.Sprintf("  %-14s %6d\n", k, st.Status[k])
+	}
+
+	s += "\nJoins and expirations by month\n"
+	for _, ms := range st.Months {
+		s += fmt.Sprintf("  %-14s %6d joined %6d expired\n", ms.Month, ms.Joined, ms.Expired)
+	}
+
+	s += "\nRenewal rate year over year\n"
+	for _, rs := range st.Renewal {
+		s += fmt.Sprintf("  %-14d %6d of %6d renewed %5.1f%%\n", rs.Year, rs.Renewed, rs.Members, rs.Rate)
+	}
+
+	s += "\nTrial conversion\n"
+	s += fmt.Sprintf("  %-14s %6d of %6d ended trials converted %5.1f%%, %d on trial\n", "trials", st.Trials.Converted, st.Trials.Ended, st.Trials.Rate, st.Trials.Current)
+
+	s += "\nPlatform users that are members\n"
+	for _, rs := range st.Platforms {
+		s += fmt.Sprintf("  %-6s %s %6d of %6d users %5.1f%% (%d matched)\n", rs.Platform, rs.Date, rs.Members, rs.Users, rs.Share, rs.Matched)
+	}
+
+	return s
+}
+
+// Format the statistics as rows of a CSV table with the columns section, key, count, total and rate
+func (st *Stats) Rows() [][]string {
+
+	rows := [][]string{{"section", "key", "count", "total", "rate"}}
+	itoa := strconv.Itoa
+	ftoa := func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) }
+
+	for _, k := range sortedKeys(st.Status) {
+		rows = append(rows, []string{"status", k, itoa(st.Status[k]), itoa(st.Total), ftoa(rate(st.Status[k], st.Total))})
+	}
+	for _, ms := range st.Months {
+		rows = append(rows, []string{"joined", ms.Month, itoa(ms.Joined), "", ""})
+	}
+	for _, ms := range st.Months {
+		rows = append(rows, []string{"expired", ms.Month, itoa(ms.Expired), "", ""})
+	}
+	for _, rs := range st.Renewal {
+		rows = append(rows, []string{"renewal", itoa(rs.Year), itoa(rs.Renewed), itoa(rs.Members), ftoa(rs.Rate)})
+	}
+	rows = append(rows, []string{"trials", "converted", itoa(st.Trials.Converted), itoa(st.Trials.Ended), ftoa(st.Trials.Rate)})
+	rows = append(rows, []string{"trials", "current", itoa(st.Trials.Current), itoa(st.Trials.Trials), ""})
+	for _, rs := range st.Platforms {
+		rows = append(rows, []string{rs.Platform, rs.Date, itoa(rs.Members), itoa(rs.Users), ftoa(rs.Share)})
+	}
+
+	return rows
+}
+
+func sortedKeys(m map[string]int) []string {
+
+	kl := make([]string, 0, len(m))
+	for k := range m {
+		kl = append(kl, k)
+	}
+	sort.Strings(kl)
+
+	return kl
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"svtc-sync/pkg/models"
+)
+
+// Status of platform users that could not be matched to a member record
+const statusNotFound = "Not Found"
+
+// --------------------------------------------------------------------------------------------
+
+// Summary of an actives sync or platform check run, to be posted to a Slack channel. Rows hold the full report
+// of the run, which is optionally attached as CSV file.
+type Summary struct {
+	Title    string         // Title of the run, e.g. "Slack check"
+	Counts   map[string]int // Number of records by status
+	New      []string       // New members (actives sync)
+	NotFound []string       // Platform users not found, that were found (or not present) in the previous run
+	Errors   int            // Number of records that failed to process
+	Header   []string       // Column names of the report
+	Rows     [][]string     // Report rows
+}
+
+// Format the summary as Slack mrkdwn text
+func (s *Summary) Text() string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "*svtc-sync: %s* (%s)\n", s.Title, time.Now().Local().Format("2006-01-02 15:04"))
+
+	keys := make([]string, 0, len(s.Counts))
+	for k := range s.Counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "• %s: %d\n", k, s.Counts[k])
+	}
+
+	if s.New != nil {
+		fmt.Fprintf(&b, "*New members:* %d\n", len(s.New))
+		for _, n := range s.New {
+			fmt.Fprintf(&b, "    %s\n", n)
+		}
+	}
+
+	if s.NotFound != nil {
+		fmt.Fprintf(&b, "*Newly Not Found:* %d\n", len(s.NotFound))
+		for _, n := range s.NotFound {
+			fmt.Fprintf(&b, "    %s\n", n)
+		}
+	}
+
+	fmt.Fprintf(&b, "*Errors:* %d\n", s.Errors)
+
+	return b.String()
+}
+
+// Format the report rows as CSV
+func (s *Summary) CSV() ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	err := w.Write(s.Header)
+	if err != nil {
+		return nil, fmt.Errorf("write csv failed: %w", err)
+	}
+	err = w.WriteAll(s.Rows)
+	if err != nil {
+		return nil, fmt.Errorf("write csv failed: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Post the summary of a run to the Slack channel specified via the post option, and attach the full report as
+// CSV file if the csv option is set.
+func (app *Application) postSummary(s *Summary) error {
+
+	err := app.SlackMemberAPI.PostMessage(app.Ctx, app.Config.Post, s.Text())
+	if err != nil {
+		app.ErrorLog.Printf("[PostMessage] %s", err)
+		return err
+	}
+	app.InfoLog.Printf("[postSummary] Posted summary to Slack channel %s", app.Config.Post)
+
+	if app.Config.CSV {
+
+		data, err := s.CSV()
+		if err != nil {
+			return err
+		}
+
+		filename := fmt.Sprintf("svtc-sync-%s.csv", time.Now().Local().Format("2006-01-02"))
+		err = app.SlackMemberAPI.UploadFile(app.Ctx, app.Config.Post, filename, s.Title, data)
+		if err != nil {
+			app.ErrorLog.Printf("[UploadFile] %s", err)
+			return err
+		}
+		app.InfoLog.Printf("[postSummary] Attached report %s to Slack channel %s", filename, app.Config.Post)
+
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Summarize the matches of a platform check. Users not found are compared to the most recent snapshot of the
+// platform to determine those that are newly not found. With store set a new snapshot is stored, but only if
+// the check is unfiltered, i.e. no status or expire date filter applied, so that snapshots reflect the complete
+// match results.
+func (app *Application) summarize(platform string, mtl []*Match, store bool) (*Summary, error) {
+
+	unfiltered := models.StatusMap[app.Config.Output] == "" && app.Config.Expire == "1963-11-04"
+
+	s := &Summary{
+		Title:  fmt.Sprintf("%s check", strings.ToUpper(platform[:1])+platform[1:]),
+		Counts: map[string]int{},
+		Header: []string{"platform", "identity", "name", "num", "status", "expired"},
+	}
+
+	users := []*models.RunUser{}
+	for _, mt := range mtl {
+		u := &models.RunUser{
+			Identity: mt.Identity(),
+			Name:     strings.TrimSpace(mt.FirstName + " " + mt.LastName),
+			Status:   statusNotFound,
+		}
+		if len(mt.Members) > 0 {
+			u.Num = mt.Members[0].Num
+			u.Status = string(mt.Members[0].Status)
+			u.Expired = mt.Members[0].Expired
+		}
+		users = append(users, u)
+
+		s.Counts[u.Status]++
+		s.Rows = append(s.Rows, []string{platform, u.Identity, u.Name, u.Num, u.Status, u.Expired})
+	}
+
+	// Snapshots and the comparison of not found users only apply to unfiltered matches
+	if !unfiltered {
+		if store {
+			app.InfoLog.Printf("[summarize] Snapshot NOT stored, the %s check is filtered", platform)
+		}
+		return s, nil
+	}
+
+	// Compare to previous snapshot
+	rl, err := app.RunSQL.List(platform, 1)
+	if err != nil {
+		app.ErrorLog.Printf("[Run SQL] %s", err)
+		return nil, err
+	}
+
+	previous := map[string]string{}
+	if len(rl) > 0 {
+		ul, err := app.RunSQL.Users(rl[0].ID)
+		if err != nil {
+			app.ErrorLog.Printf("[Run SQL] %s", err)
+			return nil, err
+		}
+		for _, u := range ul {
+			previous[u.Identity] = u.Status
+		}
+	}
+
+	s.NotFound = []string{}
+	for _, u := range users {
+		if u.Status == statusNotFound && previous[u.Identity] != statusNotFound {
+			s.NotFound = append(s.NotFound, u.Name)
+		}
+	}
+
+	if !store {
+		return s, nil
+	}
+
+	run := &models.Run{
+		Date:     time.Now().Local().Format("2006-01-02 15:04:05"),
+		Platform: platform,
+		Total:    len(users),
+	}
+	_, err = app.RunSQL.Insert(run, users)
+	if err != nil {
+		app.ErrorLog.Printf("[Run SQL] %s", err)
+		return nil, err
+	}
+	app.InfoLog.Printf("[summarize] Stored snapshot of %d %s users", len(users), platform)
+
+	return s, nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"svtc-sync/pkg/models"
+	"svtc-sync/pkg/models/api"
+)
+
+// Number of days a result of the ClubExpress member status api is cached
+const statusCacheDays = 7
+
+// Minimum interval between requests to the ClubExpress member status api
+const statusInterval = time.Second
+
+// --------------------------------------------------------------------------------------------
+
+// Verify Slack users that are Not Found or Expired in the reference DB with the ClubExpress member status api,
+// looked up by their Slack email, and report those where ClubExpress disagrees with the DB. Not Found users are
+// only verified if the check is not filtered by status. Results are cached, and requests rate limited.
+func (app *Application) verifyExpress(mtl []*Match, summary *Summary) error {
+
+	app.InfoLog.Printf("[verifyExpress] Verifying Not Found and Expired users with the ClubExpress member status api \n\n")
+
+	verified, disagree, failed := 0, 0, 0
+	last := time.Time{}
+
+	for _, mt := range mtl {
+
+		local := statusNotFound
+		if len(mt.Members) > 0 {
+			local = string(mt.Members[0].Status)
+		}
+		if mt.Email == "" || (local == statusNotFound && models.StatusMap[app.Config.Output] != "") || (local != statusNotFound && local != string(models.StatusExpired)) {
+			continue
+		}
+
+		s, err := app.ExpressStatusSQL.Get(mt.Email, statusCacheDays)
+		if err != nil {
+			app.ErrorLog.Printf("[ExpressStatus SQL] %s", err)
+			return err
+		}
+
+		if s == nil {
+
+			// Space out requests that are not answered from the cache
+			if wait := statusInterval - time.Since(last); wait > 0 {
+				err = app.sleep(wait)
+				if err != nil {
+					return err
+				}
+			}
+			last = time.Now()
+
+			code, err := app.ExpressMemberAPI.GetStatus(app.Ctx, 0, mt.Email)
+			if err != nil {
+				app.ErrorLog.Printf("[GetStatus] %s", err)
+				return err
+			}
+
+			// Errors of the api are reported, but not cached
+			if code == 0 || code == -3 {
+				fmt.Printf("%s DB: %s, ClubExpress: %s \n", mt.Label(), local, api.ExpressStatusName(code))
+				failed++
+				continue
+			}
+
+			s = &models.ExpressStatus{Email: mt.Email, Code: code, Checked: time.Now().Format("2006-01-02 15:04:05")}
+			err = app.ExpressStatusSQL.Upsert(s)
+			if err != nil {
+				app.ErrorLog.Printf("[ExpressStatus SQL] %s", err)
+				return err
+			}
+		}
+
+		verified++
+
+		remote := api.ExpressStatusName(s.Code)
+		if (local == statusNotFound && remote == "No Match") || local == remote {
+			continue
+		}
+
+		disagree++
+		fmt.Printf("%s DB: %s, ClubExpress: %s (checked %s) \n", mt.Label(), local, remote, s.Checked)
+	}
+
+	app.InfoLog.Printf("[verifyExpress] Verified %d users, %d disagree with the DB, %d failed \n", verified, disagree, failed)
+
+	summary.Counts["Disagree with ClubExpress"] = disagree
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -1,8 +1,9 @@
 module svtc-sync
 
-go 1.14
+go 1.24.0
 
 require (
 	github.com/gocarina/gocsv v0.0.0-20221216233619-1fea7ae8d380
 	github.com/mattn/go-sqlite3 v1.14.16
+	golang.org/x/crypto v0.45.0
 )
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
@@ -8,10 +9,13 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"svtc-sync/app"
 	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
 
 	"svtc-sync/pkg/models/api"
 	"svtc-sync/pkg/models/sqlite"
@@ -43,17 +47,115 @@ func main() {
 	// Flag to output out unprocessed JSON data of active members from ClubExpress
 	flag.BoolVar(&cfg.Raw, "raw", false, "Option to output raw active member JSON data from ClubExpress")
 
-	// Flag to output only result of active member sync, NOT commit updates to DB
-	flag.BoolVar(&cfg.Preview, "pre", false, "Option to only preview results of active member sync")
+	// Flag to output only result of active member sync or merge, NOT commit updates to DB
+	flag.BoolVar(&cfg.Preview, "pre", false, "Option to only preview results of active member sync or merge")
+
+	// Suppress individuals in check output that have been contacted within the specified number of days
+	flag.IntVar(&cfg.Recent, "recent", 0, "Suppress individuals contacted within this number of days")
+
+	// Flag to mark recently contacted individuals in check output instead of suppressing them
+	flag.BoolVar(&cfg.Mark, "mark", false, "Mark instead of suppress recently contacted individuals")
+
+	// Details of an outreach record to log, i.e. the channel, template (or reason), notes and date of contact
+	flag.StringVar(&cfg.Via, "via", "email", "Channel used to contact an individual, e.g. email, slack, phone")
+	flag.StringVar(&cfg.Reason, "reason", "", "Reason (or name of the template) of an outreach record")
+	flag.StringVar(&cfg.Tmpl, "template", "", "Template file of composed, sent and Slack messages, built-in if not set")
+	flag.StringVar(&cfg.Notes, "notes", "", "Notes for an outreach record or member edit")
+	flag.StringVar(&cfg.Date, "date", "", "Date of an outreach record (YYYY-MM-DD), defaults to today")
+
+	// Sender and output options of composed reminder messages, either as individual .eml files or a single mbox
+	flag.StringVar(&cfg.From, "from", "", "Sender address of composed messages")
+	flag.StringVar(&cfg.Eml, "eml", "", "Directory to write composed messages to as .eml files")
+	flag.StringVar(&cfg.Mbox, "mbox", "", "File to write composed messages to in mbox format")
+
+	// SMTP relay (host:port) without authentication to send messages to, e.g. a local MailHog instance.
+	// Overrides the SMTP credentials file.
+	flag.StringVar(&cfg.SMTP, "smtp", "", "SMTP relay host:port without authentication, overrides credentials file")
+
+	// Limit the number of messages sent per minute
+	flag.IntVar(&cfg.Rate, "rate", 30, "Maximum number of messages sent per minute")
+
+	// Post a summary of an actives sync or platform check to a Slack channel, optionally with the full report as CSV
+	flag.StringVar(&cfg.Post, "post", "", "Slack channel ID to post a run summary to")
+	flag.BoolVar(&cfg.CSV, "csv", false, "Attach the full report as CSV file to the posted summary")
+	flag.BoolVar(&cfg.Snapshot, "snapshot", false, "Store a snapshot of the results of an unfiltered check")
+
+	// Network address of the http server receiving requests from Slack, e.g. events
+	flag.StringVar(&cfg.Addr, "addr", ":8080", "Network address of the http server receiving Slack requests")
+
+	// Safety limit for the number of changes to apply to a Slack user group or channel
+	flag.IntVar(&cfg.Max, "max", 10, "Maximum number of changes applied to a Slack user group or channel")
+
+	// Slack policy for long expired or not found users, i.e. warn by direct message, then convert to guest or deactivate
+	flag.IntVar(&cfg.Grace, "grace", 90, "Number of days after expiration before the Slack policy applies")
+	flag.IntVar(&cfg.Runs, "runs", 3, "Number of days with runs Not Found before the Slack policy applies")
+	flag.IntVar(&cfg.Wait, "wait", 14, "Number of days after a policy action before the next action is taken")
+	flag.StringVar(&cfg.Action, "action", "warn", "Maximum Slack policy action: warn, guest or deactivate")
+
+	// Slack web api base URL, e.g. to use a local stand-in of the (admin) api for testing
+	flag.StringVar(&cfg.SlackAPI, "slack-api", "https://slack.com/api/", "Slack web api base URL")
+
+	// OAuth redirect URL for the auth command, e.g. an https tunnel to the localhost callback server (required by Slack)
+	flag.StringVar(&cfg.Redirect, "redirect", "", "OAuth redirect URL, defaults to http://localhost:<port>/callback")
+
+	// Credential store, i.e. plaintext files, environment variables (SVTC_<NAME>) or files encrypted with the
+	// passphrase set in the SVTC_PASSPHRASE environment variable
+	flag.StringVar(&cfg.Store, "store", "file", "Credential store: file, env or crypt")
+	flag.StringVar(&cfg.Secrets, "secrets", "./.secret", "Directory of the file and crypt credential stores")
+
+	// Archive of fetched versions of the actives JSON file, a version (file or checksum) to sync from, and the
+	// maximum drop of the actives count before a sync is refused
+	flag.StringVar(&cfg.Archive, "archive", "./archive", "Directory to archive versions of the actives JSON file in")
+	flag.StringVar(&cfg.File, "file", "", "Actives JSON file or checksum of an archived version to sync from")
+	flag.IntVar(&cfg.Drop, "drop", 10, "Maximum drop in percent of the actives count from the last sync")
+
+	// Expiration policy of new and updated active members, i.e. calendar year, rolling months or feed by membership type
+	flag.StringVar(&cfg.Expiry, "expiry", "", "Expiration policy file (JSON), defaults to end of calendar year")
+
+	// Verify Slack users Not Found or Expired against the live ClubExpress member status api
+	flag.BoolVar(&cfg.Verify, "verify", false, "Verify Slack users Not Found or Expired with the ClubExpress member status api")
+
+	// Output format of reports, e.g. stats
+	flag.StringVar(&cfg.Format, "format", "text", "Output format of reports: text, json or csv")
+	flag.BoolVar(&cfg.PII, "include-pii", false, "Include member emails in reports, masked otherwise")
+
+	// Order and number of search results
+	flag.StringVar(&cfg.Sort, "sort", "num", "Comma separated columns to sort search results by, prefix - for descending order")
+	flag.IntVar(&cfg.Limit, "limit", 0, "Maximum number of search results, 0 for no limit")
 
 	// Custom usage output, override standard flag.Usage function
 	flag.Usage = func() {
 		fmt.Printf("Usage: \n")
 		fmt.Printf("  svtc-sync -h \n")
-		fmt.Printf("  svtc-sync [-db file] -actives [-raw] [-pre] \n")
+		fmt.Printf("  svtc-sync [-db file] -actives [-raw] [-pre] [-archive dir] [-file file|checksum] [-drop percent] [-expiry file] [-post channel [-csv]] \n")
+		fmt.Printf("  svtc-sync [-db file] feeds \n")
+		fmt.Printf("  svtc-sync [-db file] [-format text|json|csv] stats \n")
+		fmt.Printf("  svtc-sync [-db file] [-include-pii] report html [file] \n")
+		fmt.Printf("  svtc-sync [-db file] [-recent days] [-email] forecast \n")
+		fmt.Printf("  svtc-sync [-db file] [-format text|json|csv] (audit|doctor) \n")
+		fmt.Printf("  svtc-sync [-db file] [-pre] merge keepNum dropNum \n")
+		fmt.Printf("  svtc-sync [-db file] member show num \n")
+		fmt.Printf("  svtc-sync [-db file] [-sort columns] [-limit n] [-format text|json|csv] [-email] search [expression] \n")
+		fmt.Printf("  svtc-sync [-db file] [-pre] [-notes text] member (edit num --field=value ...|deactivate num|reactivate num) \n")
 		fmt.Printf("  svtc-sync [-db file] (ref|alias) \n")
 		fmt.Printf("  svtc-sync [-db file] [-out NF|DUP] (strava|slack) \n")
-		fmt.Printf("  svtc-sync [-db file] [-out EXP|ACT|TRI] [-exp date] [-email] (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-db file] [-out NF|EXP] [-verify] slack \n")
+		fmt.Printf("  svtc-sync [-db file] [-out status] [-exp date] [-email] (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-db file] [-recent days [-mark]] [-out ...] (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-db file] [-post channel [-csv]] [-snapshot] [-out ...] (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-db file] outreach \n")
+		fmt.Printf("  svtc-sync [-db file] [-via channel] [-reason text] [-notes text] [-date date] outreach add (num|(slack|strava) identity) \n")
+		fmt.Printf("  svtc-sync [-db file] -out EXP|TRI [-exp date] [-recent days] [-template file] -from addr (-eml dir|-mbox file) compose (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-db file] -out EXP|TRI [-exp date] [-recent days] [-template file] -from addr [-smtp host:port] [-rate n] [-pre] send (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-db file] [-notes reason] dnc [(add|remove) email] \n")
+		fmt.Printf("  svtc-sync [-db file] -out EXP|TRI [-exp date] [-recent days] [-template file] [-rate n] [-pre] notify slack \n")
+		fmt.Printf("  svtc-sync [-db file] [-addr host:port] [-post channel] serve \n")
+		fmt.Printf("  svtc-sync [-db file] [-pre] [-max n] slack sync-group (usergroup|channel) \n")
+		fmt.Printf("  svtc-sync [-db file] [-pre] [-grace days] [-runs n] [-wait days] [-action warn|guest|deactivate] [-template file] slack policy \n")
+		fmt.Printf("  svtc-sync [-db file] slack actions \n")
+		fmt.Printf("  svtc-sync [-addr host:port] [-redirect url] auth (strava|slack) \n")
+		fmt.Printf("  svtc-sync [-store file|env|crypt] [-secrets dir] creds check \n")
+		fmt.Printf("  svtc-sync [-store file|crypt] [-secrets dir] [-pre] creds import dir \n")
 	}
 
 	flag.Parse()
@@ -62,7 +164,14 @@ func main() {
 	errorLog := log.New(os.Stderr, "ERROR ", log.Ldate|log.Ltime)
 
 	// Check if output option is in the list of supported options, print usage info and exit if not
-	err := helpers.CheckArgs(&cfg.Output, cfg.Output, []string{"NF", "DUP", "EXP", "TRI", "ACT", ""})
+	err := helpers.CheckArgs(&cfg.Output, cfg.Output, append([]string{"NF", "DUP", ""}, models.StatusOptions()...))
+	if err != nil {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	// Check if format option is in the list of supported report formats
+	err = helpers.CheckArgs(&cfg.Format, cfg.Format, []string{"text", "json", "csv"})
 	if err != nil {
 		flag.Usage()
 		os.Exit(0)
@@ -75,19 +184,120 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Assign last cli argument as operator to specify source of member data to validate (unless it is to update actives)
+	// Assign first non-flag cli argument as operator to specify source of member data to validate (unless it is to
+	// update actives). Any remaining arguments are passed on to the operator, e.g. for outreach add.
 	// Exit and print usage info if not specified.
 	// Check if operator is in list of supported platforms,exit and print usage info if not
-	if len(os.Args) > 1 {
-		if !cfg.Actives {
-			err := helpers.CheckArgs(&cfg.Source, os.Args[len(os.Args)-1], []string{"strava", "slack", "alias", "ref"})
+	if !cfg.Actives {
+		err := helpers.CheckArgs(&cfg.Source, flag.Arg(0), []string{"strava", "slack", "alias", "ref", "outreach", "compose", "send", "dnc", "notify", "serve", "auth", "creds", "feeds", "stats", "report", "forecast", "audit", "doctor", "merge", "member", "search"})
+		if err != nil {
+			flag.Usage()
+			os.Exit(0)
+		}
+		cfg.Args = flag.Args()[1:]
+	}
+
+	// --------------------------------------------------------------------------------------------
+
+	//
+	// Create custom http client to implement timeout handling for TCP connect (Dial), TLS
+	// handshake and overall end-to-end connection duration.
+	netClient := &http.Client{
+		Transport: &http.Transport{
+			// MaxIdleConns:        1000,
+			// MaxIdleConnsPerHost: 1000,
+			Dial: (&net.Dialer{
+				Timeout: 5 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	// Cancel all api requests and running operations on Ctrl-C (or SIGTERM)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		infoLog.Printf("[main] Interrupted, cancelling \n")
+		cancel()
+	}()
+
+	// Http client of all apis, retrying transient failures and logging request timing
+	apiClient := &api.Client{HTTP: netClient, Retries: -1, Log: infoLog}
+
+	// Credential store, the passphrase of the encrypted store is never passed on the command line
+	store, err := api.NewCredStore(cfg.Store, cfg.Secrets, os.Getenv("SVTC_PASSPHRASE"))
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to open credential store: %w", err))
+	}
+
+	// Credentials of the api clients, which refresh tokens on expiry or rejection and persist them to the store
+	creds := &api.CredsModel{Client: apiClient, Store: store}
+
+	// --------------------------------------------------------------------------------------------
+
+	svtc_sync := app.Application{
+		ErrorLog:         errorLog,
+		InfoLog:          infoLog,
+		Ctx:              ctx,
+		Config:           &cfg,
+		Creds:            creds,
+		ExpressMemberAPI: &api.ExpressMemberModel{Client: api.NewExpressClient(apiClient, creds.ExpressTokens())},
+		StravaAthleteAPI: &api.StravaAthleteModel{Client: &api.AuthClient{Client: apiClient, Tokens: creds.StravaTokens()}},
+		SlackMemberAPI: &api.SlackMemberModel{
+			Client:  api.NewSlackClient(apiClient, creds.SlackTokens()),
+			Admin:   api.NewSlackClient(apiClient, creds.SlackAdminTokens()),
+			BaseURL: cfg.SlackAPI,
+		},
+		MailAPI: &api.MailModel{},
+	}
+
+	// --------------------------------------------------------------------------------------------
+
+	// Credential commands do not use the reference DB, they are dispatched before it is opened and initialized
+	if !cfg.Actives && (cfg.Source == "creds" || cfg.Source == "auth") {
+
+		switch cfg.Source {
+
+		case "creds":
+
+			// Validate the credentials of the configured store, or import plaintext credential files into it
+
+			switch {
+			case len(cfg.Args) == 1 && cfg.Args[0] == "check":
+				err = svtc_sync.CheckCreds()
+			case len(cfg.Args) == 2 && cfg.Args[0] == "import":
+				err = svtc_sync.ImportCreds(cfg.Args[1])
+			default:
+				flag.Usage()
+				os.Exit(0)
+			}
 			if err != nil {
+				svtc_sync.ErrorLog.Printf("[Creds] %s", err)
+				os.Exit(1)
+			}
+
+		case "auth":
+
+			// Obtain the initial Strava user or Slack bot credentials via the OAuth flow of the platform
+
+			if len(cfg.Args) != 1 {
 				flag.Usage()
 				os.Exit(0)
 			}
+
+			err = svtc_sync.Auth(cfg.Args[0])
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[Auth] authorization failed: %s", err)
+				os.Exit(1)
+			}
+
 		}
-	} else {
-		flag.Usage()
+
 		os.Exit(0)
 	}
 
@@ -111,34 +321,59 @@ func main() {
 	}
 	defer db.Close()
 
-	//
-	// Create custom http client to implement timeout handling for TCP connect (Dial), TLS
-	// handshake and overall end-to-end connection duration.
-	netClient := &http.Client{
-		Transport: &http.Transport{
-			// MaxIdleConns:        1000,
-			// MaxIdleConnsPerHost: 1000,
-			Dial: (&net.Dialer{
-				Timeout: 5 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout: 5 * time.Second,
-		},
-		Timeout: 10 * time.Second,
+	// Create tables maintained by this tool, if they do not exist yet
+	outreachSQL := &sqlite.OutreachModel{DB: db}
+	err = outreachSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
 	}
-
-	// --------------------------------------------------------------------------------------------
-
-	svtc_sync := app.Application{
-		ErrorLog:         errorLog,
-		InfoLog:          infoLog,
-		Config:           &cfg,
-		Creds:            &api.CredsModel{Client: netClient},
-		MemberSQL:        &sqlite.MemberModel{DB: db},
-		ExpressMemberAPI: &api.ExpressMemberModel{Client: netClient},
-		StravaAthleteAPI: &api.StravaAthleteModel{Client: netClient},
-		SlackMemberAPI:   &api.SlackMemberModel{Client: netClient},
+	dncSQL := &sqlite.DoNotContactModel{DB: db}
+	err = dncSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
+	}
+	runSQL := &sqlite.RunModel{DB: db}
+	err = runSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
+	}
+	actionSQL := &sqlite.ActionModel{DB: db}
+	err = actionSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
+	}
+	feedSQL := &sqlite.FeedModel{DB: db}
+	err = feedSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
+	}
+	statusSQL := &sqlite.ExpressStatusModel{DB: db}
+	err = statusSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
+	}
+	mergeSQL := &sqlite.MergeModel{DB: db}
+	err = mergeSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
+	}
+	editSQL := &sqlite.MemberEditModel{DB: db}
+	err = editSQL.Init()
+	if err != nil {
+		errorLog.Fatal(fmt.Errorf("unable to initialize DB: %w", err))
 	}
 
+	// Models of the reference DB
+	svtc_sync.MemberSQL = &sqlite.MemberModel{DB: db}
+	svtc_sync.OutreachSQL = outreachSQL
+	svtc_sync.DoNotContactSQL = dncSQL
+	svtc_sync.RunSQL = runSQL
+	svtc_sync.ActionSQL = actionSQL
+	svtc_sync.FeedSQL = feedSQL
+	svtc_sync.ExpressStatusSQL = statusSQL
+	svtc_sync.MergeSQL = mergeSQL
+	svtc_sync.MemberEditSQL = editSQL
+
 	// --------------------------------------------------------------------------------------------
 
 	if cfg.Actives {
@@ -177,6 +412,42 @@ func main() {
 
 	case "slack":
 
+		// Keep a members-only Slack user group or channel in sync with Active and Trial members
+
+		if len(cfg.Args) == 2 && cfg.Args[0] == "sync-group" {
+			err = svtc_sync.SyncGroup(cfg.Args[1])
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[SyncGroup] cannot sync Slack user group or channel: %s", err)
+				os.Exit(1)
+			}
+			break
+		}
+
+		// Apply the policy for long expired or not found users, or list the log of policy actions taken
+
+		if len(cfg.Args) == 1 && cfg.Args[0] == "policy" {
+			err = svtc_sync.ApplyPolicy()
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[ApplyPolicy] cannot apply Slack policy: %s", err)
+				os.Exit(1)
+			}
+			break
+		}
+
+		if len(cfg.Args) == 1 && cfg.Args[0] == "actions" {
+			err = svtc_sync.ListActions()
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[ListActions] unable to list policy actions from Reference DB: %s", err)
+				os.Exit(1)
+			}
+			break
+		}
+
+		if len(cfg.Args) > 0 {
+			flag.Usage()
+			os.Exit(0)
+		}
+
 		// Check Slack workspace users against the current reference Sqlite3 database and
 		// output results in a format that is determined by configuration flags and options.
 
@@ -219,6 +490,219 @@ func main() {
 			os.Exit(1)
 		}
 
+	case "search":
+
+		// Output the valid members matching a filter expression over the member columns, e.g.
+		// status in (Expired, Trial) and expired >= 2023-01-01 and city = "San Jose"
+
+		err = svtc_sync.Search(cfg.Args)
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Search] unable to search member records: %s", err)
+			os.Exit(1)
+		}
+
+	case "outreach":
+
+		// Output all outreach records, most recent first, or log a new contact against a member number
+		// or platform identity.
+
+		if len(cfg.Args) == 0 {
+			err = svtc_sync.ListOutreach()
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[ListOutreach] unable to list outreach records from Reference DB: %s", err)
+				os.Exit(1)
+			}
+		} else {
+			err = svtc_sync.AddOutreach(cfg.Args)
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[AddOutreach] unable to log outreach record to Reference DB: %s", err)
+				os.Exit(1)
+			}
+		}
+
+	case "compose":
+
+		// Render a reminder message per member matched on the specified platform and write the results
+		// as .eml files or a mbox file, to be imported into an email client and sent by volunteers.
+
+		if len(cfg.Args) != 1 {
+			flag.Usage()
+			os.Exit(0)
+		}
+
+		err = svtc_sync.Compose(cfg.Args[0])
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Compose] unable to compose messages: %s", err)
+			os.Exit(1)
+		}
+
+	case "send":
+
+		// Render a reminder message per member matched on the specified platform and deliver it via the
+		// configured SMTP relay, skipping members on the do-not-contact list or already sent to.
+
+		if len(cfg.Args) != 1 || cfg.Rate <= 0 {
+			flag.Usage()
+			os.Exit(0)
+		}
+
+		err = svtc_sync.Send(cfg.Args[0])
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Send] unable to send messages: %s", err)
+			os.Exit(1)
+		}
+
+	case "dnc":
+
+		// Output the do-not-contact list, or add / remove an email address
+
+		if len(cfg.Args) == 0 {
+			err = svtc_sync.ListDoNotContact()
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[ListDoNotContact] unable to list do-not-contact records from Reference DB: %s", err)
+				os.Exit(1)
+			}
+		} else {
+			err = svtc_sync.EditDoNotContact(cfg.Args)
+			if err != nil {
+				svtc_sync.ErrorLog.Printf("[EditDoNotContact] unable to update do-not-contact list: %s", err)
+				os.Exit(1)
+			}
+		}
+
+	case "notify":
+
+		// Send a templated renewal message to Slack workspace users that are matched to Expired or Trial
+		// members, via direct message.
+
+		if len(cfg.Args) != 1 || cfg.Args[0] != "slack" || cfg.Rate <= 0 {
+			flag.Usage()
+			os.Exit(0)
+		}
+
+		err = svtc_sync.NotifySlack()
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[NotifySlack] unable to notify Slack users: %s", err)
+			os.Exit(1)
+		}
+
+	case "serve":
+
+		// Start an http server to receive requests from Slack, i.e. events for new and changed workspace
+		// users that are checked against the reference DB, and the /member slash command.
+
+		err = svtc_sync.Serve()
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Serve] server failed: %s", err)
+			os.Exit(1)
+		}
+
+	case "feeds":
+
+		// List the archived versions of the ClubExpress actives JSON file
+
+		err = svtc_sync.ListFeeds()
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[ListFeeds] unable to list archived actives files: %s", err)
+			os.Exit(1)
+		}
+
+	case "stats":
+
+		// Report membership statistics, i.e. counts by status, joins and expirations, renewal and trial conversion
+		// rates, and the share of platform users that are members
+
+		err = svtc_sync.Stats()
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Stats] unable to report membership statistics: %s", err)
+			os.Exit(1)
+		}
+
+	case "forecast":
+
+		// List members expiring in the next 30, 60 and 90 days with their likelihood of renewal, or their emails
+		// for a pre-expiry campaign
+
+		err = svtc_sync.Forecast()
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Forecast] unable to forecast renewals: %s", err)
+			os.Exit(1)
+		}
+
+	case "audit", "doctor":
+
+		// Check the member and alias tables for data quality issues, and list them by priority with suggested fixes
+
+		err = svtc_sync.Audit()
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Audit] unable to audit the reference DB: %s", err)
+			os.Exit(1)
+		}
+
+	case "merge":
+
+		// Merge a duplicate member record into the record of the same person to keep, i.e. move aliases and
+		// history, invalidate the duplicate and redirect future imports of its member number
+
+		if len(cfg.Args) != 2 {
+			flag.Usage()
+			os.Exit(0)
+		}
+
+		err = svtc_sync.Merge(cfg.Args[0], cfg.Args[1])
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Merge] unable to merge member records: %s", err)
+			os.Exit(1)
+		}
+
+	case "member":
+
+		// Show all columns, aliases, platform links and history of a single member record, or edit, deactivate or
+		// reactivate it. Edits are validated and logged.
+
+		if len(cfg.Args) < 2 {
+			flag.Usage()
+			os.Exit(0)
+		}
+
+		switch {
+		case cfg.Args[0] == "show" && len(cfg.Args) == 2:
+			err = svtc_sync.ShowMember(cfg.Args[1])
+		case cfg.Args[0] == "edit":
+			err = svtc_sync.EditMember(cfg.Args[1], cfg.Args[2:])
+		case cfg.Args[0] == "deactivate" && len(cfg.Args) == 2:
+			err = svtc_sync.SetMemberActive(cfg.Args[1], false)
+		case cfg.Args[0] == "reactivate" && len(cfg.Args) == 2:
+			err = svtc_sync.SetMemberActive(cfg.Args[1], true)
+		default:
+			flag.Usage()
+			os.Exit(0)
+		}
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[Member] unable to %s member record: %s", cfg.Args[0], err)
+			os.Exit(1)
+		}
+
+	case "report":
+
+		// Render statistics, platform presence and Not Found / Expired platform users into an HTML file, to an
+		// optional file name
+
+		if len(cfg.Args) < 1 || len(cfg.Args) > 2 || cfg.Args[0] != "html" {
+			flag.Usage()
+			os.Exit(0)
+		}
+		file := ""
+		if len(cfg.Args) == 2 {
+			file = cfg.Args[1]
+		}
+
+		err = svtc_sync.ReportHTML(file)
+		if err != nil {
+			svtc_sync.ErrorLog.Printf("[ReportHTML] unable to write HTML report: %s", err)
+			os.Exit(1)
+		}
+
 	}
 
 	os.Exit(0)
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Source of the token an api client authenticates with, e.g. backed by the credential store
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)   // Current token, refreshed beforehand if known to be expired
+	Refresh(ctx context.Context) (string, error) // New token after the current one was rejected, persisted to the store
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Http client that authenticates each request with the token of its source. If a request is rejected as not
+// authorized, the token is refreshed and the request retried once.
+type AuthClient struct {
+	Client       *Client
+	Tokens       TokenSource
+	Authorize    func(req *http.Request, token string) // Adds the token to a request, defaults to a bearer token
+	Unauthorized func(resp *http.Response) bool        // Detects a rejected token, defaults to status 401
+}
+
+// Send a request authenticated with the current token, refresh the token and retry once if rejected. Requests
+// with a body must be created with http.NewRequestWithContext from a bytes or strings reader, so that it can be
+// resent.
+func (c *AuthClient) Do(req *http.Request) (*http.Response, error) {
+
+	token, err := c.Tokens.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get access token: %w", err)
+	}
+
+	resp, err := c.Client.Do(c.authorize(req, token))
+	if err != nil || !c.unauthorized(resp) {
+		return resp, err
+	}
+
+	// Discard the rejected response, so that the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	token, err = c.Tokens.Refresh(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("request not authorized and token refresh failed: %w", err)
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("unable to reset request body for retry: %w", err)
+		}
+	}
+
+	return c.Client.Do(c.authorize(retry, token))
+}
+
+// Returns a copy of the request that carries the token
+func (c *AuthClient) authorize(req *http.Request, token string) *http.Request {
+
+	r := req.Clone(req.Context())
+	r.Body = req.Body
+
+	if c.Authorize != nil {
+		c.Authorize(r, token)
+	} else {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return r
+}
+
+func (c *AuthClient) unauthorized(resp *http.Response) bool {
+
+	if c.Unauthorized != nil {
+		return c.Unauthorized(resp)
+	}
+
+	return resp.StatusCode == http.StatusUnauthorized
+}
+
+// --------------------------------------------------------------------------------------------
@@ -2,32 +2,75 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"svtc-sync/pkg/models"
 )
 
 type CredsModel struct {
-	Client *http.Client
+	Client *Client
+	Store  CredStore // Backend the credentials are read from and written to, e.g. a FileStore
 }
 
+// Names of the credentials held in the store
 const (
-	cfileStrava  = "./.secret/user_creds_strava.json"
-	cfileSlack   = "./.secret/bot_creds_slack.json"
-	cfileExpress = "./.secret/club_creds_express.json"
-	cfileAPI     = "./.secret/api_creds.json"
+	CredsStrava  = "user_creds_strava"
+	CredsSlack   = "bot_creds_slack"
+	CredsExpress = "club_creds_express"
+	CredsAPI     = "api_creds"
+	CredsSMTP    = "smtp_creds"
 )
 
+// All credentials, in the order they are checked
+var CredNames = []string{CredsAPI, CredsStrava, CredsSlack, CredsExpress, CredsSMTP}
+
+// --------------------------------------------------------------------------------------------
+
+// Read a credential from the store and unmarshal it into v
+func (m *CredsModel) read(name string, v interface{}) error {
+
+	data, err := m.Store.Read(name)
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", name, err)
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	return nil
+}
+
+// Marshal v and write it to the store as credential
+func (m *CredsModel) write(name string, v interface{}) error {
+
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return fmt.Errorf("marshal json data failed: %w", err)
+	}
+
+	err = m.Store.Write(name, data)
+	if err != nil {
+		return fmt.Errorf("write %s failed: %w", name, err)
+	}
+
+	return nil
+}
+
 // --------------------------------------------------------------------------------------------
 
-func (m *CredsModel) CheckStravaExp() (string, error) {
+func (m *CredsModel) CheckStravaExp(ctx context.Context) (string, error) {
 
 	c, err := m.ReadStravaUserCreds()
 	if err != nil {
@@ -36,7 +79,7 @@ func (m *CredsModel) CheckStravaExp() (string, error) {
 
 	if c.Expires_At < int(time.Now().Unix()) {
 
-		rc, err := m.RefreshStravaAccess(c.Refresh_Token)
+		rc, err := m.RefreshStravaAccess(ctx, c.Refresh_Token)
 		if err != nil {
 			return "", fmt.Errorf("refresh Strava access failed: %w", err)
 		}
@@ -57,14 +100,14 @@ func (m *CredsModel) CheckStravaExp() (string, error) {
 
 // --------------------------------------------------------------------------------------------
 
-func (m *CredsModel) RefreshStravaAccess(refresh_token string) (*models.StravaCreds, error) {
+func (m *CredsModel) RefreshStravaAccess(ctx context.Context, refresh_token string) (*models.StravaCreds, error) {
 
 	creds, err := m.ReadStravaClientCreds()
 	if err != nil {
 		return nil, fmt.Errorf("unable to read Strava client creds: %w", err)
 	}
 
-	req_url := "http://www.strava.com/oauth/token"
+	req_url := "https://www.strava.com/oauth/token"
 
 	query := map[string]string{
 		"client_id":     strconv.Itoa(creds.Client_ID),
@@ -78,7 +121,7 @@ func (m *CredsModel) RefreshStravaAccess(refresh_token string) (*models.StravaCr
 		return nil, fmt.Errorf("marshal json query data failed: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", req_url, bytes.NewBuffer(post))
+	req, err := http.NewRequestWithContext(ctx, "POST", req_url, bytes.NewBuffer(post))
 	if err != nil {
 		return nil, fmt.Errorf("creation of new POST request to Strava api failed: %w", err)
 	}
@@ -91,15 +134,18 @@ func (m *CredsModel) RefreshStravaAccess(refresh_token string) (*models.StravaCr
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 401 {
-		return nil, fmt.Errorf("401: request to Strava api not authorized")
-	}
-
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read result of call to Strava api: %w", err)
 	}
 
+	// A revoked or invalid refresh token is answered with a 400 and a JSON error description, which must not
+	// replace the stored tokens
+	if resp.StatusCode != 200 {
+		err = errors.New(string(body))
+		return nil, fmt.Errorf("non-200 response from Strava api: %w", err)
+	}
+
 	err = json.Unmarshal(body, &creds)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
@@ -110,49 +156,84 @@ func (m *CredsModel) RefreshStravaAccess(refresh_token string) (*models.StravaCr
 
 // --------------------------------------------------------------------------------------------
 
-func (m *CredsModel) ReadStravaClientCreds() (*models.StravaCreds, error) {
+// Exchange the authorization code of the Strava OAuth flow for the initial refresh and access token. Returns
+// the user credentials, i.e. client credentials and tokens, to be written to the user creds file.
+func (m *CredsModel) ExchangeStravaCode(ctx context.Context, code string) (*models.StravaCreds, error) {
 
-	creds := &models.Creds{}
+	creds, err := m.ReadStravaClientCreds()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Strava client creds: %w", err)
+	}
 
-	file, err := os.Open(cfileAPI)
+	req_url := "https://www.strava.com/oauth/token"
+
+	query := map[string]string{
+		"client_id":     strconv.Itoa(creds.Client_ID),
+		"client_secret": creds.Client_Secret,
+		"code":          code,
+		"grant_type":    "authorization_code",
+	}
+
+	post, err := json.Marshal(query)
 	if err != nil {
-		return nil, fmt.Errorf("file open failed: %w", err)
+		return nil, fmt.Errorf("marshal json query data failed: %w", err)
 	}
-	defer file.Close()
 
-	data, err := ioutil.ReadAll(file)
+	req, err := http.NewRequestWithContext(ctx, "POST", req_url, bytes.NewBuffer(post))
 	if err != nil {
-		return nil, fmt.Errorf("file read failed: %w", err)
+		return nil, fmt.Errorf("creation of new POST request to Strava api failed: %w", err)
 	}
 
-	err = json.Unmarshal(data, &creds)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST request to Strava api execution failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read result of call to Strava api: %w", err)
+	}
+
+	// An invalid or already used code is answered with a 400 and a JSON error description
+	if resp.StatusCode != 200 {
+		err = errors.New(string(body))
+		return nil, fmt.Errorf("non-200 response from Strava api: %w", err)
+	}
+
+	err = json.Unmarshal(body, &creds)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
 	}
 
-	return &creds.Strava, nil
+	return creds, nil
 }
 
 // --------------------------------------------------------------------------------------------
 
-func (m *CredsModel) ReadStravaUserCreds() (*models.StravaCreds, error) {
+func (m *CredsModel) ReadStravaClientCreds() (*models.StravaCreds, error) {
 
-	creds := &models.StravaCreds{}
+	creds := &models.Creds{}
 
-	file, err := os.Open(cfileStrava)
+	err := m.read(CredsAPI, creds)
 	if err != nil {
-		return nil, fmt.Errorf("file open failed: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	data, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("file read failed: %w", err)
-	}
+	return &creds.Strava, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (m *CredsModel) ReadStravaUserCreds() (*models.StravaCreds, error) {
+
+	creds := &models.StravaCreds{}
 
-	err = json.Unmarshal(data, &creds)
+	err := m.read(CredsStrava, creds)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
+		return nil, err
 	}
 
 	return creds, nil
@@ -162,51 +243,130 @@ func (m *CredsModel) ReadStravaUserCreds() (*models.StravaCreds, error) {
 
 func (m *CredsModel) WriteStravaUserCreds(creds *models.StravaCreds) error {
 
-	data, err := json.MarshalIndent(creds, "", " ")
-	if err != nil {
-		return fmt.Errorf("marshal json data failed: %w", err)
-	}
+	return m.write(CredsStrava, creds)
+}
 
-	err = ioutil.WriteFile(cfileStrava, data, 0644)
+// --------------------------------------------------------------------------------------------
+
+func (m *CredsModel) GetSlackAccess() (string, error) {
+
+	creds, err := m.ReadSlackBotCreds()
 	if err != nil {
-		return fmt.Errorf("file write failed: %w", err)
+		return "", fmt.Errorf("unable to read Slack user credentials %w", err)
 	}
 
-	return nil
+	return creds.Access_Token, nil
 }
 
 // --------------------------------------------------------------------------------------------
 
-func (m *CredsModel) GetSlackAccess() (string, error) {
+func (m *CredsModel) GetSlackAdminAccess() (string, error) {
 
 	creds, err := m.ReadSlackBotCreds()
 	if err != nil {
 		return "", fmt.Errorf("unable to read Slack user credentials %w", err)
 	}
 
-	return creds.Access_Token, nil
+	return creds.Admin_Token, nil
 }
 
 // --------------------------------------------------------------------------------------------
 
-func (m *CredsModel) ReadSlackBotCreds() (*models.SlackCreds, error) {
+// Refresh the Slack bot token of an app with token rotation enabled via oauth.v2.access, and write the new
+// token to the store. Without rotation bot tokens do not expire, and a rejected token requires a new install.
+func (m *CredsModel) RefreshSlackAccess(ctx context.Context) (string, error) {
 
-	creds := &models.SlackCreds{}
+	creds, err := m.ReadSlackBotCreds()
+	if err != nil {
+		return "", fmt.Errorf("unable to read Slack bot credentials %w", err)
+	}
+	if creds.Refresh_Token == "" {
+		return "", fmt.Errorf("Slack token rotation not enabled, run auth slack for a new token")
+	}
 
-	file, err := os.Open(cfileSlack)
+	form := url.Values{
+		"client_id":     {creds.Client_ID},
+		"client_secret": {creds.Client_Secret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {creds.Refresh_Token},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/oauth.v2.access", strings.NewReader(form.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("file open failed: %w", err)
+		return "", fmt.Errorf("creation of new POST request to Slack web api failed: %w", err)
 	}
-	defer file.Close()
 
-	data, err := ioutil.ReadAll(file)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := m.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("file read failed: %w", err)
+		return "", fmt.Errorf("POST request to Slack web api failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	err = json.Unmarshal(data, &creds)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
+		return "", fmt.Errorf("unable to read result of call to Slack web api: %w", err)
+	}
+
+	response := models.ResponseOAuth{}
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return "", fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return "", fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+	log.Printf("[RefreshSlackAccess] Refreshed Slack Access Token \n")
+
+	creds.Access_Token = response.Access_Token
+	creds.Refresh_Token = response.Refresh_Token
+	creds.Expires_At = int(time.Now().Unix()) + response.Expires_In
+
+	err = m.WriteSlackBotCreds(creds)
+	if err != nil {
+		return "", fmt.Errorf("write Slack bot creds failed: %w", err)
+	}
+
+	return creds.Access_Token, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Read the Slack app client credentials (and signing secret) from the api credentials, as needed for
+// the OAuth v2 install flow.
+func (m *CredsModel) ReadSlackClientCreds() (*models.SlackCreds, error) {
+
+	creds := &models.Creds{}
+
+	err := m.read(CredsAPI, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &creds.Slack, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Write the Slack bot credentials to the store
+func (m *CredsModel) WriteSlackBotCreds(creds *models.SlackCreds) error {
+
+	return m.write(CredsSlack, creds)
+}
+
+// --------------------------------------------------------------------------------------------
+
+func (m *CredsModel) ReadSlackBotCreds() (*models.SlackCreds, error) {
+
+	creds := &models.SlackCreds{}
+
+	err := m.read(CredsSlack, creds)
+	if err != nil {
+		return nil, err
 	}
 
 	return creds, nil
@@ -240,20 +400,23 @@ func (m *CredsModel) ReadExpressCreds() (*models.ExpressCreds, error) {
 
 	creds := &models.ExpressCreds{}
 
-	file, err := os.Open(cfileExpress)
+	err := m.read(CredsExpress, creds)
 	if err != nil {
-		return nil, fmt.Errorf("file open failed: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	data, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("file read failed: %w", err)
-	}
+	return creds, nil
+}
 
-	err = json.Unmarshal(data, &creds)
+// --------------------------------------------------------------------------------------------
+
+func (m *CredsModel) ReadSMTPCreds() (*models.SMTPCreds, error) {
+
+	creds := &models.SMTPCreds{}
+
+	err := m.read(CredsSMTP, creds)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
+		return nil, err
 	}
 
 	return creds, nil
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Credentials model with a file store in a temporary directory, that sends all requests to the given function
+func newTestCreds(t *testing.T, rt roundTripFunc) *CredsModel {
+
+	dir, err := ioutil.TempDir("", "svtc-sync")
+	if err != nil {
+		t.Fatalf("temp dir failed: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	store := &FileStore{Dir: filepath.Join(dir, "secret")}
+	for name, data := range map[string]string{
+		CredsAPI:    `{"strava": {"client_id": 123, "client_secret": "secret"}}`,
+		CredsStrava: `{"client_id": 123, "client_secret": "secret", "refresh_token": "refresh", "access_token": "access", "expires_at": 1}`,
+	} {
+		err = store.Write(name, []byte(data))
+		if err != nil {
+			t.Fatalf("write creds failed: %s", err)
+		}
+	}
+
+	return &CredsModel{Client: &Client{HTTP: &http.Client{Transport: rt}}, Store: store}
+}
+
+// --------------------------------------------------------------------------------------------
+
+func TestStravaRefreshRejected(t *testing.T) {
+
+	var scheme string
+	creds := newTestCreds(t, func(req *http.Request) (*http.Response, error) {
+		scheme = req.URL.Scheme
+		body := `{"message": "Bad Request", "errors": [{"resource": "RefreshToken", "field": "refresh_token", "code": "invalid"}]}`
+		return &http.Response{StatusCode: 400, Status: "400 Bad Request", Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})
+
+	_, err := creds.StravaTokens().Refresh(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "non-200") {
+		t.Fatalf("Refresh: expected non-200 error, got %v", err)
+	}
+	if scheme != "https" {
+		t.Errorf("refresh sent via %q, expected https", scheme)
+	}
+
+	c, err := creds.ReadStravaUserCreds()
+	if err != nil {
+		t.Fatalf("read creds failed: %s", err)
+	}
+	if c.Refresh_Token != "refresh" || c.Access_Token != "access" {
+		t.Errorf("stored tokens %q %q changed by the rejected refresh", c.Refresh_Token, c.Access_Token)
+	}
+}
@@ -1,17 +1,39 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	neturl "net/url"
 	"strconv"
 
 	"svtc-sync/pkg/models"
 )
 
 type ExpressMemberModel struct {
-	Client *http.Client
+	Client *AuthClient // Authenticated with the ClubExpress access key, for the member status api
+}
+
+// Returns a client for the ClubExpress api, which expects the access key as query parameter and rejects an
+// invalid key with a 403.
+func NewExpressClient(client *Client, tokens TokenSource) *AuthClient {
+
+	return &AuthClient{
+		Client: client,
+		Tokens: tokens,
+		Authorize: func(req *http.Request, token string) {
+			q := req.URL.Query()
+			q.Set("key", token)
+			req.URL.RawQuery = q.Encode()
+		},
+		Unauthorized: func(resp *http.Response) bool {
+			return resp.StatusCode == http.StatusForbidden
+		},
+	}
 }
 
 // SVTC Club ID for ClubExpress api requests
@@ -39,18 +61,17 @@ const activesurl = "https://s3.amazonaws.com/ClubExpressClubFiles/325779/json/wr
 // -2 = no match found
 // -3 = invalid request
 //
-// We are currently not using this api due its limited scope, but the code has been developed and tested
-// to enable it, including storage and reading of the api credentials (access_key).
-func (m *ExpressMemberModel) GetStatus(Num int, Email string, access_key string) (int, error) {
+// Due to its limited scope the api is only used to verify Slack users that are Not Found or Expired in the
+// reference DB, looking them up by email (Num 0). The codes are mapped to statuses via models.StatusCodes.
+func (m *ExpressMemberModel) GetStatus(ctx context.Context, Num int, Email string) (int, error) {
 
-	// https://ws.clubexpress.com/member_status.ashx?cid={clubid}&key={access_key}
+	// https://ws.clubexpress.com/member_status.ashx?cid={clubid}&key={access_key}, key added by the client
 	url := "https://ws.clubexpress.com/member_status.ashx"
 	url += "?cid=" + strconv.Itoa(ClubIDexpress)
-	url += "&key=" + access_key
 	url += "&n=" + strconv.Itoa(Num)
-	url += "&e=" + Email
+	url += "&e=" + neturl.QueryEscape(Email)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return 0, fmt.Errorf("creation of new GET request to ClubExpress api failed: %w", err)
 	}
@@ -80,67 +101,130 @@ func (m *ExpressMemberModel) GetStatus(Num int, Email string, access_key string)
 
 }
 
+// Results of the member status api other than a member status
+var expressResults = map[int]string{
+	0:  "Internal Error",
+	-1: "Multiple Matches",
+	-2: "No Match",
+	-3: "Invalid Request",
+}
+
+// Returns the status or result of a return code of the member status api, e.g. Active for 1
+func ExpressStatusName(code int) string {
+
+	if s, ok := models.StatusCodes[code]; ok {
+		return string(s)
+	}
+	if s, ok := expressResults[code]; ok {
+		return s
+	}
+
+	return "Unknown (" + strconv.Itoa(code) + ")"
+}
+
 // --------------------------------------------------------------------------------------------
 
-// Function to read a JSON file via the provided url and deserialize it into a memberSVTC struct.
-// A new version of this file is placed at the AWS S3 hosted URL every day at 11:00 am GMT (3am PST)
-// and contains an export of all currently active members in the ClubExpress platform for SVTC.
-func (m *ExpressMemberModel) GetActives() ([]*models.MemberSVTC, error) {
+// Function to fetch the JSON file of currently active members. A new version of this file is placed at the AWS
+// S3 hosted URL every day at 11:00 am GMT (3am PST) and contains an export of all currently active members in
+// the ClubExpress platform for SVTC. The request is conditional on a change since the version with the given
+// ETag and Last-Modified date (if not empty), returns nil if the file has not been modified.
+func (m *ExpressMemberModel) FetchActives(ctx context.Context, etag string, lastModified string) (*models.Feed, error) {
 
-	ml := []*models.MemberSVTC{}
+	req, err := http.NewRequestWithContext(ctx, "GET", activesurl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creation of new GET request to ClubExpress api failed: %w", err)
+	}
 
-	resp, err := http.Get(activesurl)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	// The file is public, hence the request is sent without the access key of the client
+	resp, err := m.Client.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("could not GET active member JSON from ClubExpress api: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("non-200 response from ClubExpress api: %s", resp.Status)
+	}
+
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read result of call to ClubExpress api: %w", err)
 	}
 
-	err = json.Unmarshal(data, &ml)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
+	sum := sha256.Sum256(data)
+
+	f := &models.Feed{
+		LastModified: resp.Header.Get("Last-Modified"),
+		ETag:         resp.Header.Get("ETag"),
+		Checksum:     hex.EncodeToString(sum[:]),
+		Data:         data,
 	}
 
-	return ml, nil
+	return f, nil
 
 }
 
 // --------------------------------------------------------------------------------------------
 
-// Function reads the GET request header if a supplied url and returns the assoc key-value pairs.
-func (m *ExpressMemberModel) GetHeader() (map[string][]string, error) {
-
-	resp, err := http.Get(activesurl)
-	if err != nil {
-		return nil, fmt.Errorf("could not GET header info from ClubExpress api: %w", err)
-	}
-	defer resp.Body.Close()
+// Error of a malformed record of the actives JSON file, with the position of the record in the file
+type RecordError struct {
+	Index int    // Index of the record in the JSON array, from 0
+	Num   string // Member number of the record, if it could be read
+	Err   error
+}
 
-	return resp.Header, nil
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record at index %d: %s", e.Index, e.Err)
+}
 
+func (e *RecordError) Unwrap() error {
+	return e.Err
 }
 
-// --------------------------------------------------------------------------------------------
+// Function to deserialize the content of the actives JSON file into a list of memberSVTC structs. Each record
+// is decoded on its own, so that a malformed record does not fail the whole file; the errors of malformed
+// records are returned as RecordError, naming the position of the record in the file.
+func (m *ExpressMemberModel) ParseActives(data []byte) ([]*models.MemberSVTC, []error, error) {
 
-// Function to read the GET request body of a url and return the data as raw slice of bytes.
-func (m *ExpressMemberModel) GetActivesRaw() ([]byte, error) {
+	rl := []json.RawMessage{}
 
-	resp, err := http.Get(activesurl)
+	err := json.Unmarshal(data, &rl)
 	if err != nil {
-		return nil, fmt.Errorf("could not GET active member JSON from ClubExpress api: %w", err)
+		return nil, nil, fmt.Errorf("actives JSON is not a list of member records: %w", err)
 	}
-	defer resp.Body.Close()
 
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read result of call to ClubExpress api: %w", err)
+	ml := []*models.MemberSVTC{}
+	bad := []error{}
+
+	for i, r := range rl {
+		m := &models.MemberSVTC{}
+		err = json.Unmarshal(r, m)
+		if err != nil {
+			re := &RecordError{Index: i, Err: err}
+
+			// The member number may still be readable, e.g. if another field is malformed or it is a number
+			fields := map[string]interface{}{}
+			if json.Unmarshal(r, &fields) == nil && fields["memberNumber"] != nil {
+				re.Num = fmt.Sprint(fields["memberNumber"])
+			}
+
+			bad = append(bad, re)
+			continue
+		}
+		ml = append(ml, m)
 	}
 
-	return data, nil
+	return ml, bad, nil
 
 }
 
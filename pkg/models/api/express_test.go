@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseActives(t *testing.T) {
+
+	data := []byte(`[
+		{"memberNumber": "1001", "firstName": "Dave", "lastName": "Scott"},
+		{"memberNumber": 1002, "firstName": "Mark", "lastName": "Allen"},
+		{"memberNumber": "1003", "firstName": ["Paula"]},
+		"not a record"
+	]`)
+
+	ml, bad, err := (&ExpressMemberModel{}).ParseActives(data)
+	if err != nil {
+		t.Fatalf("ParseActives: %s", err)
+	}
+	if len(ml) != 1 || ml[0].Num != "1001" {
+		t.Errorf("parsed %d records, expected 1001 only", len(ml))
+	}
+
+	want := []struct {
+		index int
+		num   string
+	}{{1, "1002"}, {2, "1003"}, {3, ""}}
+	if len(bad) != len(want) {
+		t.Fatalf("%d malformed records, expected %d", len(bad), len(want))
+	}
+	for i, w := range want {
+		var re *RecordError
+		if !errors.As(bad[i], &re) {
+			t.Fatalf("error %v is not a RecordError", bad[i])
+		}
+		if re.Index != w.index || re.Num != w.num || re.Err == nil {
+			t.Errorf("malformed record at index %d with num %q, expected index %d with num %q", re.Index, re.Num, w.index, w.num)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"time"
+)
+
+// Defaults of the http client, used where a setting is zero (or negative for the number of retries)
+const (
+	defaultRetries = 3                      // Number of retries after the first attempt
+	defaultBackoff = 500 * time.Millisecond // Base delay, doubled with each retry
+	defaultMaxBody = 32 << 20               // Maximum size of a response body
+	maxRetryAfter  = 5 * time.Minute        // Longest Retry-After delay of a 429 response that is waited for
+)
+
+// Error returned when reading a response body that exceeds the maximum size
+var ErrBodyTooLarge = errors.New("response body exceeds size limit")
+
+// --------------------------------------------------------------------------------------------
+
+// Http client used by all apis. Requests are bound to the context they are created with, so that they are
+// cancelled e.g. on Ctrl-C. Transient failures are retried with jittered exponential backoff, i.e. network errors
+// and 5xx (or 429) responses of idempotent requests, and network errors before a request was sent for all others,
+// so that e.g. a message is never posted twice. The Retry-After delay of a 429 response is honored. Response
+// bodies are capped in size, and the timing of each request is logged.
+type Client struct {
+	HTTP    *http.Client  // Underlying client with connection timeouts
+	Retries int           // Number of retries after the first attempt, 0 for none, negative for the default
+	Backoff time.Duration // Base delay before the first retry, doubled with each further retry
+	MaxBody int64         // Maximum size of a response body in bytes
+	Log     *log.Logger   // Logger of request timing, none if nil
+}
+
+// Send a request, retrying transient failures. Requests with a body must be created with
+// http.NewRequestWithContext from a bytes or strings reader, so that it can be resent.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+
+	ctx := req.Context()
+
+	retries, backoff := c.Retries, c.Backoff
+	if retries < 0 {
+		retries = defaultRetries
+	}
+	if backoff == 0 {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		// Track whether the request was written, a request that was not sent can be retried regardless of method
+		var sent bool
+		trace := &httptrace.ClientTrace{WroteRequest: func(httptrace.WroteRequestInfo) { sent = true }}
+
+		start := time.Now()
+		resp, err := c.HTTP.Do(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+
+		// The query is omitted from the log, as it may hold an access key
+		status := "error"
+		if err == nil {
+			status = resp.Status
+		}
+		if c.Log != nil {
+			c.Log.Printf("[http] %s %s%s %s %dms", req.Method, req.URL.Host, req.URL.Path, status, time.Since(start).Milliseconds())
+		}
+
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		retry := transient(resp, err) && (idempotent(req.Method) || (err != nil && !sent))
+		delay := time.Duration(rand.Int63n(int64(backoff << uint(attempt)))) // Full jitter, i.e. a random delay up to the exponential backoff
+		if retry && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			if after, ok := retryAfter(resp, time.Now()); ok {
+				delay = after
+				retry = after <= maxRetryAfter
+			}
+		}
+
+		if !retry || attempt >= retries {
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = &limitedBody{r: resp.Body, n: c.maxBody()}
+			return resp, nil
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, c.maxBody()))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("unable to reset request body for retry: %w", err)
+			}
+		}
+	}
+}
+
+func (c *Client) maxBody() int64 {
+
+	if c.MaxBody == 0 {
+		return defaultMaxBody
+	}
+
+	return c.MaxBody
+}
+
+// Network errors and server side errors are transient, as well as rate limiting
+func transient(resp *http.Response, err error) bool {
+
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// Requests that have the same effect if sent more than once (RFC 9110), and may be retried after a response
+func idempotent(method string) bool {
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// Delay of the Retry-After header of a response, either in seconds or as http date
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Response body that fails with ErrBodyTooLarge once more than n bytes are read
+type limitedBody struct {
+	r io.ReadCloser
+	n int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+
+	if b.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+
+	n, err := b.r.Read(p)
+	b.n -= int64(n)
+	if b.n < 0 {
+		return n, ErrBodyTooLarge
+	}
+
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.r.Close()
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server that responds with the given statuses in turn, and counts the requests received
+type statusServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+}
+
+func newStatusServer(t *testing.T, header http.Header, statuses ...int) *statusServer {
+
+	s := &statusServer{}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		status := statuses[len(statuses)-1]
+		if s.requests < len(statuses) {
+			status = statuses[s.requests]
+		}
+		s.requests++
+		s.mu.Unlock()
+
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *statusServer) count() int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// --------------------------------------------------------------------------------------------
+
+func TestClientRetries(t *testing.T) {
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		retries  int
+		header   http.Header
+		statuses []int
+		requests int
+		status   int
+	}{
+		{"get retried", "GET", 2, nil, []int{500, 502, 200}, 3, 200},
+		{"get retries exhausted", "GET", 2, nil, []int{500}, 3, 500},
+		{"zero retries", "GET", 0, nil, []int{500, 200}, 1, 500},
+		{"post not retried", "POST", 2, nil, []int{500, 200}, 1, 500},
+		{"post rate limited", "POST", 2, nil, []int{429, 200}, 1, 429},
+		{"retry after honored", "GET", 2, http.Header{"Retry-After": {"0"}}, []int{429, 200}, 2, 200},
+		{"retry after too long", "GET", 2, http.Header{"Retry-After": {"3600"}}, []int{429, 200}, 1, 429},
+		{"client error", "GET", 2, nil, []int{404}, 1, 404},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			srv := newStatusServer(t, tc.header, tc.statuses...)
+			c := &Client{HTTP: srv.Client(), Retries: tc.retries, Backoff: time.Millisecond}
+
+			req, err := http.NewRequest(tc.method, srv.URL, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatalf("new request failed: %s", err)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("Do: %s", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Errorf("status %d, expected %d", resp.StatusCode, tc.status)
+			}
+			if got := srv.count(); got != tc.requests {
+				t.Errorf("%d requests sent, expected %d", got, tc.requests)
+			}
+		})
+	}
+}
+
+func TestClientRetriesUnsentPost(t *testing.T) {
+
+	// Nothing listens on the address of a closed server, so the request fails before it is sent
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	attempts := 0
+	c := &Client{HTTP: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return http.DefaultTransport.RoundTrip(req)
+	})}, Retries: 2, Backoff: time.Millisecond}
+
+	req, err := http.NewRequest("POST", url, strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("new request failed: %s", err)
+	}
+	_, err = c.Do(req)
+	if err == nil {
+		t.Fatalf("Do: expected connection error")
+	}
+	if attempts != 3 {
+		t.Errorf("%d attempts, expected the unsent request to be retried twice", attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, want := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"Wed, 01 Sep 2021 12:00:30 GMT": 30 * time.Second,
+		"Wed, 01 Sep 2021 11:00:00 GMT": 0,
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": {value}}}
+		got, ok := retryAfter(resp, now)
+		if !ok || got != want {
+			t.Errorf("Retry-After %q: %s %v, expected %s", value, got, ok, want)
+		}
+	}
+
+	for _, value := range []string{"", "soon", "-5"} {
+		resp := &http.Response{Header: http.Header{"Retry-After": {value}}}
+		if _, ok := retryAfter(resp, now); ok {
+			t.Errorf("Retry-After %q: expected no delay", value)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
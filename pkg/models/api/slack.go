@@ -1,35 +1,78 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 
 	"svtc-sync/pkg/models"
 )
 
 type SlackMemberModel struct {
-	Client *http.Client
+	Client  *AuthClient // Authenticated with the bot token
+	Admin   *AuthClient // Authenticated with the admin user token, for admin api methods
+	BaseURL string      // Slack web api base URL, defaults to https://slack.com/api/ (may point to a local stand-in)
+}
+
+// Returns a client for the Slack web api that authenticates with a bot or user token. Slack rejects invalid
+// or expired tokens with a 200 response and an error, which is detected in addition to a 401.
+func NewSlackClient(client *Client, tokens TokenSource) *AuthClient {
+	return &AuthClient{Client: client, Tokens: tokens, Unauthorized: slackUnauthorized}
+}
+
+func slackUnauthorized(resp *http.Response) bool {
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+
+	// Peek at the error of the response, and restore the body for the caller
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	response := models.Response{}
+	if json.Unmarshal(body, &response) != nil || response.Ok {
+		return false
+	}
+
+	return response.Error == "invalid_auth" || response.Error == "token_expired" || response.Error == "token_revoked"
 }
 
 // --------------------------------------------------------------------------------------------
 
-// Function to query the SLack Web API and list all users / members in the SVTC workspace.
-func (m *SlackMemberModel) List(access_token string) ([]models.Member, error) {
+// Returns the URL of a Slack web api method
+func (m *SlackMemberModel) endpoint(method string) string {
 
-	url := "https://slack.com/api/users.list"
+	if m.BaseURL == "" {
+		return "https://slack.com/api/" + method
+	}
+
+	return strings.TrimSuffix(m.BaseURL, "/") + "/" + method
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// --------------------------------------------------------------------------------------------
+
+// Function to query the SLack Web API and list all users / members in the SVTC workspace.
+func (m *SlackMemberModel) List(ctx context.Context) ([]models.Member, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", m.endpoint("users.list"), nil)
 	if err != nil {
 		return nil, fmt.Errorf("creation of new GET request to Slack web api failed: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+access_token)
 
 	resp, err := m.Client.Do(req)
 	if err != nil {
@@ -69,3 +112,518 @@ func (m *SlackMemberModel) Sort(ml []models.Member) {
 	})
 
 }
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the Slack Web API for a single workspace user by their user ID
+func (m *SlackMemberModel) Info(ctx context.Context, user_id string) (*models.Member, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", m.endpoint("users.info")+"?user="+user_id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creation of new GET request to Slack web api failed: %w", err)
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET request to Slack web api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read result of call to Slack web api: %w", err)
+	}
+
+	response := models.ResponseUser{}
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return nil, fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return &response.User, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to open (or resume) a direct message conversation with a workspace user and return its channel ID.
+// Requires the im:write scope.
+func (m *SlackMemberModel) OpenDM(ctx context.Context, user_id string) (string, error) {
+
+	response := models.ResponseChannel{}
+
+	err := m.post(ctx, m.Client, "conversations.open", map[string]interface{}{"users": user_id}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return "", fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return response.Channel.ID, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to post a plain text message to a channel or direct message conversation. Requires the chat:write scope.
+func (m *SlackMemberModel) PostMessage(ctx context.Context, channel string, text string) error {
+
+	response := models.Response{}
+
+	err := m.post(ctx, m.Client, "chat.postMessage", map[string]interface{}{"channel": channel, "text": text}, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to respond to a slash command with an ephemeral message, i.e. only visible to the user of the command,
+// via the response_url of the command. The URL authorizes the response, no token is sent.
+func (m *SlackMemberModel) Respond(ctx context.Context, response_url string, text string) error {
+
+	data, err := json.Marshal(map[string]string{"response_type": "ephemeral", "text": text})
+	if err != nil {
+		return fmt.Errorf("marshal json query data failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", response_url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creation of new POST request to Slack response url failed: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := m.Client.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST request to Slack response url failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("non-200 response from Slack response url: %s", resp.Status)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to upload a file (e.g. a CSV report) and share it in a channel, specified by its ID. The upload is done
+// in three steps: request an upload URL, post the content and complete the upload. Requires the files:write scope.
+func (m *SlackMemberModel) UploadFile(ctx context.Context, channel_id string, filename string, title string, content []byte) error {
+
+	// Step 1: Request upload URL. This method only accepts form encoded arguments.
+	form := url.Values{}
+	form.Set("filename", filename)
+	form.Set("length", strconv.Itoa(len(content)))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.endpoint("files.getUploadURLExternal"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("creation of new POST request to Slack web api failed: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST request to Slack web api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read result of call to Slack web api: %w", err)
+	}
+
+	upload := models.ResponseUpload{}
+
+	err = json.Unmarshal(body, &upload)
+	if err != nil {
+		return fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	if !upload.Ok {
+		err = errors.New(upload.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	// Step 2: Post file content to upload URL
+	req, err = http.NewRequestWithContext(ctx, "POST", upload.UploadURL, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("creation of new POST request to Slack upload url failed: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err = m.Client.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST request to Slack upload url failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("non-200 response from Slack upload url: %s", resp.Status)
+	}
+
+	// Step 3: Complete upload and share file in channel
+	payload := map[string]interface{}{
+		"files":      []map[string]string{{"id": upload.FileID, "title": title}},
+		"channel_id": channel_id,
+	}
+
+	response := models.Response{}
+
+	err = m.post(ctx, m.Client, "files.completeUploadExternal", payload, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to list the user IDs of a user group. Requires the usergroups:read scope.
+func (m *SlackMemberModel) UsergroupMembers(ctx context.Context, usergroup_id string) ([]string, error) {
+
+	response := models.ResponseUsergroup{}
+
+	err := m.get(ctx, m.Client, "usergroups.users.list", url.Values{"usergroup": {usergroup_id}}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return nil, fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return response.Users, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to replace the complete list of users of a user group. Requires the usergroups:write scope.
+func (m *SlackMemberModel) UpdateUsergroup(ctx context.Context, usergroup_id string, user_ids []string) error {
+
+	response := models.Response{}
+
+	payload := map[string]interface{}{"usergroup": usergroup_id, "users": strings.Join(user_ids, ",")}
+
+	err := m.post(ctx, m.Client, "usergroups.users.update", payload, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to list the user IDs of all members of a channel, following pagination cursors.
+// Requires the channels:read (or groups:read for private channels) scope.
+func (m *SlackMemberModel) ChannelMembers(ctx context.Context, channel_id string) ([]string, error) {
+
+	members := []string{}
+	cursor := ""
+
+	for {
+
+		response := models.ResponseConversation{}
+
+		params := url.Values{"channel": {channel_id}, "limit": {"200"}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		err := m.get(ctx, m.Client, "conversations.members", params, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		if !response.Ok {
+			err = errors.New(response.Error)
+			return nil, fmt.Errorf("non-OK response status from Slack web api: %w", err)
+		}
+
+		members = append(members, response.Members...)
+
+		cursor = response.ResponseMetadata.NextCursor
+		if cursor == "" {
+			break
+		}
+
+	}
+
+	return members, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to invite users to a channel. Requires the channels:manage (or groups:write) scope.
+func (m *SlackMemberModel) Invite(ctx context.Context, channel_id string, user_ids []string) error {
+
+	response := models.Response{}
+
+	payload := map[string]interface{}{"channel": channel_id, "users": strings.Join(user_ids, ",")}
+
+	err := m.post(ctx, m.Client, "conversations.invite", payload, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to remove a user from a channel. Requires the channels:manage (or groups:write) scope.
+func (m *SlackMemberModel) Kick(ctx context.Context, channel_id string, user_id string) error {
+
+	response := models.Response{}
+
+	payload := map[string]interface{}{"channel": channel_id, "user": user_id}
+
+	err := m.post(ctx, m.Client, "conversations.kick", payload, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to convert a workspace user to a (multi-channel) guest via the admin api. Requires a user token
+// with the admin.users:write scope on an Enterprise Grid organization.
+func (m *SlackMemberModel) SetGuest(ctx context.Context, team_id string, user_id string) error {
+
+	response := models.Response{}
+
+	err := m.post(ctx, m.Admin, "admin.users.setRestricted", map[string]interface{}{"team_id": team_id, "user_id": user_id}, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to remove (deactivate) a workspace user via the admin api. Requires a user token with the
+// admin.users:write scope on an Enterprise Grid organization.
+func (m *SlackMemberModel) Deactivate(ctx context.Context, team_id string, user_id string) error {
+
+	response := models.Response{}
+
+	err := m.post(ctx, m.Admin, "admin.users.remove", map[string]interface{}{"team_id": team_id, "user_id": user_id}, &response)
+	if err != nil {
+		return err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to call a Slack web api method via GET with query parameters, using the client of the bot or admin
+// token, and unmarshal the response into v
+func (m *SlackMemberModel) get(ctx context.Context, c *AuthClient, method string, params url.Values, v interface{}) error {
+
+	req_url := m.endpoint(method) + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", req_url, nil)
+	if err != nil {
+		return fmt.Errorf("creation of new GET request to Slack web api failed: %w", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET request to Slack web api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read result of call to Slack web api: %w", err)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to call a Slack web api method via POST with a JSON payload, using the client of the bot or admin
+// token, and unmarshal the response into v
+func (m *SlackMemberModel) post(ctx context.Context, c *AuthClient, method string, payload interface{}, v interface{}) error {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal json query data failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.endpoint(method), bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("creation of new POST request to Slack web api failed: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST request to Slack web api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read result of call to Slack web api: %w", err)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to exchange the temporary code of the OAuth v2 install flow for the bot token of the app via the
+// oauth.v2.access method. The client credentials are sent as form values, no token is required.
+func (m *SlackMemberModel) OAuthAccess(ctx context.Context, client_id string, client_secret string, code string, redirect_uri string) (*models.ResponseOAuth, error) {
+
+	form := url.Values{
+		"client_id":     {client_id},
+		"client_secret": {client_secret},
+		"code":          {code},
+		"redirect_uri":  {redirect_uri},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.endpoint("oauth.v2.access"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creation of new POST request to Slack web api failed: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := m.Client.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST request to Slack web api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read result of call to Slack web api: %w", err)
+	}
+
+	response := &models.ResponseOAuth{}
+
+	err = json.Unmarshal(body, response)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal json data failed: %w", err)
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return nil, fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return response, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to check the bot token, or the admin token if admin is set, via the auth.test method. Returns the
+// workspace and user the token belongs to.
+func (m *SlackMemberModel) AuthTest(ctx context.Context, admin bool) (*models.ResponseAuth, error) {
+
+	c := m.Client
+	if admin {
+		c = m.Admin
+	}
+
+	response := &models.ResponseAuth{}
+
+	err := m.get(ctx, c, "auth.test", url.Values{}, response)
+	if err != nil {
+		return nil, err
+	}
+
+	if !response.Ok {
+		err = errors.New(response.Error)
+		return nil, fmt.Errorf("non-OK response status from Slack web api: %w", err)
+	}
+
+	return response, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
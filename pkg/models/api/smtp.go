@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+
+	"svtc-sync/pkg/models"
+)
+
+type MailModel struct{}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to send a single RFC 5322 formatted message via the configured SMTP relay. STARTTLS is used if
+// the relay supports it. Authentication is only attempted if a user name is configured, which allows the use
+// of a local catch-all server (e.g. MailHog) for testing.
+func (m *MailModel) Send(creds *models.SMTPCreds, from string, to string, msg []byte) error {
+
+	if creds == nil || creds.Host == "" {
+		return fmt.Errorf("no SMTP relay configured")
+	}
+
+	addr := net.JoinHostPort(creds.Host, strconv.Itoa(creds.Port))
+
+	var auth smtp.Auth
+	if creds.Username != "" {
+		auth = smtp.PlainAuth("", creds.Username, creds.Password, creds.Host)
+	}
+
+	err := smtp.SendMail(addr, auth, from, []string{to}, msg)
+	if err != nil {
+		return fmt.Errorf("send mail to %s via %s failed: %w", to, addr, err)
+	}
+
+	return nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to check the SMTP relay settings without sending a message, i.e. connect, use STARTTLS if supported
+// and authenticate if a user name is configured.
+func (m *MailModel) Check(creds *models.SMTPCreds) error {
+
+	if creds == nil || creds.Host == "" {
+		return fmt.Errorf("no SMTP relay configured")
+	}
+
+	addr := net.JoinHostPort(creds.Host, strconv.Itoa(creds.Port))
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s failed: %w", addr, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: creds.Host})
+		if err != nil {
+			return fmt.Errorf("STARTTLS with %s failed: %w", addr, err)
+		}
+	}
+
+	if creds.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", creds.Username, creds.Password, creds.Host))
+		if err != nil {
+			return fmt.Errorf("authentication with %s failed: %w", addr, err)
+		}
+	}
+
+	return c.Quit()
+
+}
+
+// --------------------------------------------------------------------------------------------
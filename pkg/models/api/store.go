@@ -0,0 +1,213 @@
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Store of credentials. Each credential is a JSON document identified by its name, e.g. user_creds_strava.
+type CredStore interface {
+	Read(name string) ([]byte, error)
+	Write(name string, data []byte) error
+}
+
+// Error returned by a store when a credential does not exist
+var ErrNoCreds = errors.New("credentials not found")
+
+// Prefix of the environment variables of the env store
+const envPrefix = "SVTC_"
+
+// Returns the credential store of the given kind, i.e. file (plaintext files in dir), env (environment
+// variables) or crypt (files in dir encrypted with the passphrase).
+func NewCredStore(kind string, dir string, passphrase string) (CredStore, error) {
+
+	switch kind {
+	case "file":
+		return &FileStore{Dir: dir}, nil
+	case "env":
+		return &EnvStore{Prefix: envPrefix}, nil
+	case "crypt":
+		if passphrase == "" {
+			return nil, fmt.Errorf("no passphrase set for encrypted credentials")
+		}
+		return &CryptStore{Dir: dir, Passphrase: passphrase}, nil
+	}
+
+	return nil, fmt.Errorf("unknown credential store: %s", kind)
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Credentials stored as plaintext JSON files <name>.json in a directory, readable by the owner only
+type FileStore struct {
+	Dir string // Directory holding the credential files, e.g. ./.secret
+}
+
+func (s *FileStore) Read(name string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, name+".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrNoCreds
+	}
+	if err != nil {
+		return nil, fmt.Errorf("file read failed: %w", err)
+	}
+
+	return data, nil
+}
+
+func (s *FileStore) Write(name string, data []byte) error {
+
+	err := os.MkdirAll(s.Dir, 0700)
+	if err != nil {
+		return fmt.Errorf("create directory failed: %w", err)
+	}
+
+	file := filepath.Join(s.Dir, name+".json")
+
+	err = ioutil.WriteFile(file, data, 0600)
+	if err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+
+	// WriteFile does not change the mode of an existing file, e.g. one written with 0644 before
+	return os.Chmod(file, 0600)
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Credentials held in environment variables <Prefix><NAME>, e.g. SVTC_USER_CREDS_STRAVA, each holding the JSON
+// document. Writes, i.e. refreshed tokens, only update the environment of the running process.
+type EnvStore struct {
+	Prefix string // Prefix of the variable names, e.g. SVTC_
+}
+
+func (s *EnvStore) variable(name string) string {
+	return s.Prefix + strings.ToUpper(name)
+}
+
+func (s *EnvStore) Read(name string) ([]byte, error) {
+
+	v, ok := os.LookupEnv(s.variable(name))
+	if !ok || v == "" {
+		return nil, ErrNoCreds
+	}
+
+	return []byte(v), nil
+}
+
+func (s *EnvStore) Write(name string, data []byte) error {
+	return os.Setenv(s.variable(name), string(data))
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Parameters of the passphrase-encrypted store. The key is derived via PBKDF2-HMAC-SHA256 from the passphrase
+// and a random salt per file, each file holds salt | nonce | AES-256-GCM ciphertext.
+const (
+	cryptSaltSize   = 16
+	cryptKeySize    = 32
+	cryptIterations = 200000
+)
+
+// Credentials stored as passphrase-encrypted files <name>.json.enc in a directory, readable by the owner only
+type CryptStore struct {
+	Dir        string // Directory holding the encrypted credential files
+	Passphrase string // Passphrase the encryption keys are derived from
+}
+
+func (s *CryptStore) Read(name string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, name+".json.enc"))
+	if os.IsNotExist(err) {
+		return nil, ErrNoCreds
+	}
+	if err != nil {
+		return nil, fmt.Errorf("file read failed: %w", err)
+	}
+
+	if len(data) < cryptSaltSize {
+		return nil, fmt.Errorf("encrypted file %s too short", name)
+	}
+	salt, data := data[:cryptSaltSize], data[cryptSaltSize:]
+
+	gcm, err := s.cipher(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted file %s too short", name)
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, data, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("decryption of %s failed, wrong passphrase?", name)
+	}
+
+	return plain, nil
+}
+
+func (s *CryptStore) Write(name string, data []byte) error {
+
+	salt := make([]byte, cryptSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return fmt.Errorf("unable to generate salt: %w", err)
+	}
+
+	gcm, err := s.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	// The credential name is authenticated, so that files cannot be swapped
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, data, []byte(name))
+
+	err = os.MkdirAll(s.Dir, 0700)
+	if err != nil {
+		return fmt.Errorf("create directory failed: %w", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(s.Dir, name+".json.enc"), out, 0600)
+	if err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the AES-GCM cipher keyed with the passphrase and salt
+func (s *CryptStore) cipher(salt []byte) (cipher.AEAD, error) {
+
+	if s.Passphrase == "" {
+		return nil, fmt.Errorf("no passphrase set for encrypted credentials")
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(s.Passphrase), salt, cryptIterations, cryptKeySize, sha256.New))
+	if err != nil {
+		return nil, fmt.Errorf("creation of cipher failed: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// --------------------------------------------------------------------------------------------
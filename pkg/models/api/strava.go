@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,7 +15,7 @@ import (
 )
 
 type StravaAthleteModel struct {
-	Client *http.Client
+	Client *AuthClient // Authenticated with the Strava user access token
 }
 
 const (
@@ -25,19 +26,17 @@ const (
 // --------------------------------------------------------------------------------------------
 
 // Function to query the Strava public Club API endpoint to obtain information on SVTC (based on the CLub ID)
-func (m *StravaAthleteModel) GetClub(access_token string) (*models.Club, error) {
+func (m *StravaAthleteModel) GetClub(ctx context.Context) (*models.Club, error) {
 
 	// https://www.strava.com/api/v3/clubs/{id}
 	url := "https://www.strava.com/api/v3/clubs/"
 	url += strconv.Itoa(ClubIDstrava)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creation of new GET request to Strava api failed: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+access_token)
-
 	resp, err := m.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("GET request to Strava api failed: %w", err)
@@ -75,7 +74,7 @@ func (m *StravaAthleteModel) GetClub(access_token string) (*models.Club, error)
 // Function to query the Strava public Club API endpoint to obtain a list of athletes affilated
 // with SVTC (based on the CLub ID). The number of records to query is based on the number of members
 // returned by the GetClub function.
-func (m *StravaAthleteModel) List(count int, access_token string) ([]models.Athlete, error) {
+func (m *StravaAthleteModel) List(ctx context.Context, count int) ([]models.Athlete, error) {
 
 	// https://www.strava.com/api/v3/clubs/{id}/members
 	url := "https://www.strava.com/api/v3/clubs/"
@@ -84,13 +83,11 @@ func (m *StravaAthleteModel) List(count int, access_token string) ([]models.Athl
 	url += "?page=1"
 	url += "&per_page=" + strconv.Itoa(count)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creation of new GET request to Strava api failed: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+access_token)
-
 	resp, err := m.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("GET request to Strava api failed: %w", err)
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// --------------------------------------------------------------------------------------------
+
+// Token source of a token as stored, that is never refreshed, e.g. to check credentials without changing the
+// store
+type staticTokens struct {
+	token string
+}
+
+func StaticTokens(token string) TokenSource {
+	return &staticTokens{token: token}
+}
+
+func (t *staticTokens) Token(ctx context.Context) (string, error) {
+	return t.token, nil
+}
+
+func (t *staticTokens) Refresh(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("access token rejected")
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Token source of the Strava user access token, refreshed via the stored refresh token
+type stravaTokens struct {
+	creds *CredsModel
+}
+
+func (m *CredsModel) StravaTokens() TokenSource {
+	return &stravaTokens{creds: m}
+}
+
+func (t *stravaTokens) Token(ctx context.Context) (string, error) {
+	return t.creds.CheckStravaExp(ctx)
+}
+
+// The access token was rejected before its stored expiration, e.g. revoked or rotated early by Strava
+func (t *stravaTokens) Refresh(ctx context.Context) (string, error) {
+
+	c, err := t.creds.ReadStravaUserCreds()
+	if err != nil {
+		return "", fmt.Errorf("unable to read Strava user credentials %w", err)
+	}
+
+	rc, err := t.creds.RefreshStravaAccess(ctx, c.Refresh_Token)
+	if err != nil {
+		return "", fmt.Errorf("refresh Strava access failed: %w", err)
+	}
+	log.Printf("[RefreshStravaAccess] Refreshed rejected Strava Access Token \n")
+
+	err = t.creds.WriteStravaUserCreds(rc)
+	if err != nil {
+		return "", fmt.Errorf("write Strava user creds failed: %w", err)
+	}
+
+	return rc.Access_Token, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Token source of the Slack bot token, refreshed if token rotation is enabled for the app
+type slackTokens struct {
+	creds *CredsModel
+}
+
+func (m *CredsModel) SlackTokens() TokenSource {
+	return &slackTokens{creds: m}
+}
+
+func (t *slackTokens) Token(ctx context.Context) (string, error) {
+
+	c, err := t.creds.ReadSlackBotCreds()
+	if err != nil {
+		return "", fmt.Errorf("unable to read Slack bot credentials %w", err)
+	}
+
+	if c.Refresh_Token != "" && c.Expires_At < int(time.Now().Unix()) {
+		return t.creds.RefreshSlackAccess(ctx)
+	}
+
+	return c.Access_Token, nil
+}
+
+func (t *slackTokens) Refresh(ctx context.Context) (string, error) {
+	return t.creds.RefreshSlackAccess(ctx)
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Token source of the Slack admin user token, which cannot be refreshed
+type slackAdminTokens struct {
+	creds *CredsModel
+}
+
+func (m *CredsModel) SlackAdminTokens() TokenSource {
+	return &slackAdminTokens{creds: m}
+}
+
+func (t *slackAdminTokens) Token(ctx context.Context) (string, error) {
+	return t.creds.GetSlackAdminAccess()
+}
+
+func (t *slackAdminTokens) Refresh(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("Slack admin token rejected, configure a new admin_token")
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Token source of the ClubExpress access key. The key does not expire, but is re-read from the store when
+// rejected, in case it has been replaced since it was read.
+type expressTokens struct {
+	creds *CredsModel
+	key   string
+}
+
+func (m *CredsModel) ExpressTokens() TokenSource {
+	return &expressTokens{creds: m}
+}
+
+func (t *expressTokens) Token(ctx context.Context) (string, error) {
+
+	key, err := t.creds.GetExpressAccess()
+	if err != nil {
+		return "", err
+	}
+	t.key = key
+
+	return key, nil
+}
+
+func (t *expressTokens) Refresh(ctx context.Context) (string, error) {
+
+	key, err := t.creds.GetExpressAccess()
+	if err != nil {
+		return "", err
+	}
+	if key == t.key {
+		return "", fmt.Errorf("ClubExpress access key rejected")
+	}
+	t.key = key
+
+	return key, nil
+}
+
+// --------------------------------------------------------------------------------------------
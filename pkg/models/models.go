@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // ------------------------------------------------------------------------------------------------
 type Creds struct {
 	Strava  StravaCreds  `json:"strava"`
@@ -9,7 +11,7 @@ type Creds struct {
 
 // ------------------------------------------------------------------------------------------------
 
-// The Strava credentials data struct holds the data that is returned by the Strava http://www.strava.com/oauth/token request.
+// The Strava credentials data struct holds the data that is returned by the Strava https://www.strava.com/oauth/token request.
 // Note the grant_type of "refresh_token" among other POST key/value pairs.
 type StravaCreds struct {
 	Client_ID     int    `json:"client_id"`     // Application ID, read from api_credentials.json file
@@ -26,6 +28,9 @@ type SlackCreds struct {
 	Client_Secret  string `json:"client_secret"`  // Application Secret, read from api_credentials.json file
 	Signing_Secret string `json:"signing_secret"` // Secret to verify api responses
 	Access_Token   string `json:"access_token"`   // Permanent Access Token
+	Admin_Token    string `json:"admin_token"`    // Optional user token with admin.users:write scope
+	Refresh_Token  string `json:"refresh_token"`  // Only set if token rotation is enabled for the app
+	Expires_At     int    `json:"expires_at"`     // Access Token expiration date/time, if rotated
 }
 
 type ExpressCreds struct {
@@ -33,6 +38,15 @@ type ExpressCreds struct {
 	Access_Key string `json:"access_key"` // Assigned Access Key
 }
 
+// SMTP relay credentials used to send reminder emails. Username and Password may be left empty for relays
+// that do not require authentication, e.g. a local catch-all server such as MailHog.
+type SMTPCreds struct {
+	Host     string `json:"host"`     // SMTP relay host name
+	Port     int    `json:"port"`     // SMTP relay port, e.g. 587
+	Username string `json:"username"` // SMTP auth user name
+	Password string `json:"password"` // SMTP auth password
+}
+
 // ------------------------------------------------------------------------------------------------
 
 // Strava Athlete data structure is used to hold response data from the Strava List Club Members (getClubMembersById) API call
@@ -54,34 +68,29 @@ type Club struct {
 
 // ------------------------------------------------------------------------------------------------
 
-var StatusMap = map[string]string{
-	"EXP": "Expired",
-	"ACT": "Active",
-	"TRI": "Trial",
-}
-
 // Core member structure, modeled after the ClubExpress csv export and JSON active member file. It is mainly used
 // to create unique queries for lookup and match purposes. Non relevant fields for the time being are address and
 // phone related; they are stored on new record insert, but remain unused.
 type MemberSVTC struct {
-	ID        int    `json:"id"`            // sql: id INTEGER
-	Num       string `json:"memberNumber"`  // sql: num INTEGER
-	Active    bool   `json:"active"`        // sql: active INTEGER
-	Login     string `json:"loginName"`     // sql: login TEXT
-	FirstName string `json:"firstName"`     // sql: firstname TEXT
-	Middle    string `json:"middleInitial"` // sql: middle TEXT
-	LastName  string `json:"lastName"`      // sql: lastname TEXT
-	Email     string `json:"email"`         // sql: email TEXT
-	Status    string `json:"status"`        // sql: status TEXT
-	Joined    string `json:"joined"`        // sql: joined TEXT
-	Expired   string `json:"expired"`       // sql: expired TEXT
-	Address   string `json:"address1"`      // sql: address TEXT
-	AddrExt   string `json:"address2"`      // sql: addr_ext TEXT
-	City      string `json:"city"`          // sql: city TEXT
-	State     string `json:"state"`         // sql: state TEXT
-	Zip       string `json:"zip"`           // sql: zip INTEGER
-	Mobile    string `json:"cellPhone"`     // sql: mobile TEXT
-	Phone     string `json:"phone"`         // sql: phone TEXT
+	ID        int    `json:"id"`             // sql: id INTEGER
+	Num       string `json:"memberNumber"`   // sql: num INTEGER
+	Active    bool   `json:"active"`         // sql: active INTEGER
+	Login     string `json:"loginName"`      // sql: login TEXT
+	FirstName string `json:"firstName"`      // sql: firstname TEXT
+	Middle    string `json:"middleInitial"`  // sql: middle TEXT
+	LastName  string `json:"lastName"`       // sql: lastname TEXT
+	Email     string `json:"email"`          // sql: email TEXT
+	Status    Status `json:"status"`         // sql: status TEXT
+	Joined    string `json:"joined"`         // sql: joined TEXT
+	Expired   string `json:"expired"`        // sql: expired TEXT
+	Address   string `json:"address1"`       // sql: address TEXT
+	AddrExt   string `json:"address2"`       // sql: addr_ext TEXT
+	City      string `json:"city"`           // sql: city TEXT
+	State     string `json:"state"`          // sql: state TEXT
+	Zip       string `json:"zip"`            // sql: zip INTEGER
+	Mobile    string `json:"cellPhone"`      // sql: mobile TEXT
+	Phone     string `json:"phone"`          // sql: phone TEXT
+	Type      string `json:"membershipType"` // Membership type, selects the expiration policy (not stored)
 	// MemberID  string `json:"profileLink"`   // sql: clubexpress_id INTEGER
 }
 
@@ -95,6 +104,100 @@ type MemberAlias struct {
 	Email     string // sq;: email TEXT
 }
 
+// Structure to record outreach to individuals, i.e. who was contacted, when, how and why. A record is tied to
+// either a member number or a platform identity (Slack email, Strava name), or both.
+type Outreach struct {
+	ID       int    // sql: id INTEGER
+	Num      string // sql: num TEXT
+	Platform string // sql: platform TEXT
+	Identity string // sql: identity TEXT
+	Channel  string // sql: channel TEXT
+	Date     string // sql: date TEXT
+	Template string // sql: template TEXT
+	Notes    string // sql: notes TEXT
+}
+
+// Structure of a snapshot of a check run, i.e. the date and platform checked and the number of platform users.
+// The results per platform user are held in RunUser records.
+type Run struct {
+	ID       int    // sql: id INTEGER
+	Date     string // sql: date TEXT (YYYY-MM-DD HH:MM:SS)
+	Platform string // sql: platform TEXT
+	Total    int    // sql: total INTEGER
+}
+
+// Structure of a cached result of the ClubExpress member status api, looked up by email. Code is the
+// numeric result of the api, e.g. 1 for active or -2 for no match.
+type ExpressStatus struct {
+	Email   string // sql: email TEXT (lower case)
+	Code    int    // sql: code INTEGER
+	Checked string // sql: checked TEXT (YYYY-MM-DD HH:MM:SS)
+}
+
+// Structure of an archived version of the ClubExpress actives JSON file. The content is archived gzipped in
+// File, identified by its SHA-256 Checksum. ETag and LastModified are used for conditional requests.
+type Feed struct {
+	ID           int    // sql: id INTEGER
+	Fetched      string // sql: fetched TEXT (YYYY-MM-DD HH:MM:SS)
+	LastModified string // sql: last_modified TEXT, http Last-Modified header
+	ETag         string // sql: etag TEXT, http ETag header
+	Checksum     string // sql: checksum TEXT, hex SHA-256 of the (uncompressed) content
+	Count        int    // sql: count INTEGER, number of member records
+	File         string // sql: file TEXT, path of the gzipped archive file
+	Data         []byte // Content, not stored in the DB
+}
+
+// Structure of the result of a check run for a single platform user. Status is the status of the matched member
+// record with the latest expiration date, or "Not Found".
+type RunUser struct {
+	RunID    int    // sql: run_id INTEGER
+	Identity string // sql: identity TEXT
+	Name     string // sql: name TEXT
+	Num      string // sql: num TEXT
+	Status   string // sql: status TEXT
+	Expired  string // sql: expired TEXT
+}
+
+// Structure of the merge of a duplicate member record into the surviving record of the same person, e.g. after a
+// re-registration in ClubExpress. Imports of the dropped member number are redirected to the kept number.
+type MemberMerge struct {
+	DropNum string // sql: drop_num TEXT PRIMARY KEY
+	KeepNum string // sql: keep_num TEXT
+	Date    string // sql: date TEXT (YYYY-MM-DD HH:MM:SS)
+}
+
+// Structure of the audit log of edits of single member records, i.e. one record per changed field with its old
+// and new value. Deactivation and reactivation are logged as edits of the active field.
+type MemberEdit struct {
+	ID    int    // sql: id INTEGER
+	Date  string // sql: date TEXT (YYYY-MM-DD HH:MM:SS)
+	Num   string // sql: num TEXT
+	Field string // sql: field TEXT, column of the member table
+	Old   string // sql: old TEXT
+	New   string // sql: new TEXT
+	Notes string // sql: notes TEXT
+}
+
+// Structure of the Slack policy action log, i.e. warnings and guest conversions or deactivations of Slack users.
+// Result holds "ok" or the error returned by the Slack web api.
+type Action struct {
+	ID       int    // sql: id INTEGER
+	Date     string // sql: date TEXT
+	UserID   string // sql: user_id TEXT
+	Identity string // sql: identity TEXT
+	Num      string // sql: num TEXT
+	Action   string // sql: action TEXT (warn, guest, deactivate)
+	Reason   string // sql: reason TEXT
+	Result   string // sql: result TEXT
+}
+
+// Structure of the do-not-contact list, i.e. email addresses that must not be sent any reminders
+type DoNotContact struct {
+	Email  string // sql: email TEXT
+	Date   string // sql: date TEXT
+	Reason string // sql: reason TEXT
+}
+
 // ------------------------------------------------------------------------------------------------
 
 // Slack Workspace Member / User data structures as returned by the user.list request to their web api.
@@ -111,10 +214,108 @@ type Member struct {
 	Name               string  `json:"name"`
 	Profile            Profile `json:"profile"`
 	Is_Email_Confirmed bool    `json:"is_email_confirmed"`
+	Is_Admin           bool    `json:"is_admin"` // Workspace admin
+	Is_Owner           bool    `json:"is_owner"` // Workspace owner
+	Is_Bot             bool    `json:"is_bot"`   // Bot or app user
+	Deleted            bool    `json:"deleted"`  // Deactivated user
 }
 
 type Profile struct {
-	FirstName string `json:"first_name"` // First name of team member
-	LastName  string `json:"last_name"`  // Last name of team member
-	Email     string `json:"email"`      // Team Member Email
+	FirstName string `json:"first_name"`   // First name of team member
+	LastName  string `json:"last_name"`    // Last name of team member
+	Email     string `json:"email"`        // Team Member Email
+	Display   string `json:"display_name"` // Display name, as in a mention
+	RealName  string `json:"real_name"`    // Full name, as in a mention if no display name is set
+}
+
+// Slack users.info response, holding a single workspace user
+type ResponseUser struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+	User  Member `json:"user"`
+}
+
+// Slack usergroups.users.list response, holding the user IDs of a user group
+type ResponseUsergroup struct {
+	Ok    bool     `json:"ok"`
+	Error string   `json:"error"`
+	Users []string `json:"users"`
+}
+
+// Slack conversations.members response, holding a page of user IDs of channel members
+type ResponseConversation struct {
+	Ok               bool     `json:"ok"`
+	Error            string   `json:"error"`
+	Members          []string `json:"members"`
+	ResponseMetadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"response_metadata"`
+}
+
+// Generic Slack web api response, used for methods where only the status is of interest, e.g. chat.postMessage
+type Response struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"` // Resonse body contains only this when !ok
+}
+
+// Slack conversations.open response, holding the ID of the direct message channel with a user
+type ResponseChannel struct {
+	Ok      bool    `json:"ok"`
+	Error   string  `json:"error"`
+	Channel Channel `json:"channel"`
+}
+
+type Channel struct {
+	ID string `json:"id"`
+}
+
+// Slack files.getUploadURLExternal response, holding the URL to upload file content to
+type ResponseUpload struct {
+	Ok        bool   `json:"ok"`
+	Error     string `json:"error"`
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// Slack auth.test response, identifying the workspace and user (or bot) a token belongs to
+type ResponseAuth struct {
+	Ok     bool   `json:"ok"`
+	Error  string `json:"error"`
+	Team   string `json:"team"`
+	User   string `json:"user"`
+	TeamID string `json:"team_id"`
+	UserID string `json:"user_id"`
+}
+
+// Slack oauth.v2.access response of the OAuth v2 install flow, holding the bot token of the installed app
+type ResponseOAuth struct {
+	Ok            bool   `json:"ok"`
+	Error         string `json:"error"`
+	App_ID        string `json:"app_id"`
+	Access_Token  string `json:"access_token"`  // Bot token (xoxb-)
+	Refresh_Token string `json:"refresh_token"` // Only returned if token rotation is enabled
+	Expires_In    int    `json:"expires_in"`    // Lifetime of a rotated token in seconds
+	Scope         string `json:"scope"`         // Granted bot scopes
+	Bot_User_ID   string `json:"bot_user_id"`
+	Team          struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+}
+
+// Slack Events API request body. Type is either "url_verification", with a Challenge to be echoed, or
+// "event_callback" with the actual Event.
+type EventRequest struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	TeamID    string `json:"team_id"`
+	EventID   string `json:"event_id"` // Unique ID of the event, the same for retries of a delivery
+	Event     Event  `json:"event"`
+}
+
+// Slack event, e.g. team_join or user_change. The User field holds a user object for these event types, but
+// may be a user ID for others, hence it is kept raw.
+type Event struct {
+	Type string          `json:"type"`
+	User json.RawMessage `json:"user"`
 }
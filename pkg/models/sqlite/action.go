@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"svtc-sync/pkg/models"
+)
+
+type ActionModel struct {
+	DB *sql.DB
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the action table, that logs Slack policy actions, if it does not exist yet
+func (m *ActionModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS action ("
+	query += "id INTEGER PRIMARY KEY, "
+	query += "date TEXT, "
+	query += "user_id TEXT, "
+	query += "identity TEXT, "
+	query += "num TEXT, "
+	query += "action TEXT, "
+	query += "reason TEXT, "
+	query += "result TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "action", query)
+	if err != nil {
+		return fmt.Errorf("create action table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to add a record to the action log. Date fields are expected to be "YYYY-MM-DD".
+func (m *ActionModel) Insert(a *models.Action) error {
+
+	query := "INSERT INTO action "
+	query += "(date, user_id, identity, num, action, reason, result) "
+	query += "VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	_, err := m.DB.Exec(query, a.Date, a.UserID, a.Identity, a.Num, a.Action, a.Reason, a.Result)
+	if err != nil {
+		return fmt.Errorf("insert action failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query and return the complete action log, ordered by most recent first
+func (m *ActionModel) List() ([]*models.Action, error) {
+
+	query := "SELECT id, date, user_id, identity, num, action, reason, result "
+	query += "FROM action "
+	query += "ORDER BY date DESC, id DESC"
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	actionList := []*models.Action{}
+
+	for rows.Next() {
+
+		a := &models.Action{}
+
+		err = rows.Scan(
+			&a.ID,
+			&a.Date,
+			&a.UserID,
+			&a.Identity,
+			&a.Num,
+			&a.Action,
+			&a.Reason,
+			&a.Result,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("action sql query failed: %w", err)
+		}
+
+		actionList = append(actionList, a)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return actionList, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"svtc-sync/pkg/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type DoNotContactModel struct {
+	DB *sql.DB
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the do-not-contact table if it does not exist yet. Email addresses are stored in
+// lower case and are unique.
+func (m *DoNotContactModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS dnc ("
+	query += "email TEXT PRIMARY KEY, "
+	query += "date TEXT, "
+	query += "reason TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "dnc", query)
+	if err != nil {
+		return fmt.Errorf("create dnc table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to add an email address to the do-not-contact list, returns an error if it is already listed
+func (m *DoNotContactModel) Insert(d *models.DoNotContact) error {
+
+	query := "INSERT INTO dnc (email, date, reason) VALUES (?, ?, ?)"
+
+	stmt, err := m.DB.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("prepare sql query failed: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(strings.ToLower(strings.TrimSpace(d.Email)), d.Date, d.Reason)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return fmt.Errorf("insert dnc failed: %w", errors.New("email already listed"))
+		}
+		return fmt.Errorf("insert dnc failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to remove an email address from the do-not-contact list
+func (m *DoNotContactModel) Delete(email string) error {
+
+	query := "DELETE FROM dnc WHERE email = ?"
+
+	result, err := m.DB.Exec(query, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return fmt.Errorf("sql query failed for %s: %w", email, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("sql query failed for %s: %w", email, errors.New("no matching record found"))
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query and return the complete do-not-contact list, ordered by email
+func (m *DoNotContactModel) List() ([]*models.DoNotContact, error) {
+
+	query := "SELECT email, date, reason FROM dnc ORDER BY email"
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	dncList := []*models.DoNotContact{}
+
+	for rows.Next() {
+
+		d := &models.DoNotContact{}
+
+		err = rows.Scan(
+			&d.Email,
+			&d.Date,
+			&d.Reason,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("dnc sql query failed: %w", err)
+		}
+
+		dncList = append(dncList, d)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return dncList, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"svtc-sync/pkg/models"
+)
+
+type MemberEditModel struct {
+	DB *sql.DB
+}
+
+// Columns of the member table that can be edited. The id and member number identify the record and can not be
+// changed.
+var EditColumns = []string{
+	"active", "login", "firstname", "middle", "lastname", "email", "status", "joined", "expired",
+	"address", "addr_ext", "city", "state", "zip", "mobile", "phone",
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the member_edit table, that logs edits of single member records, if it does not exist
+func (m *MemberEditModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS member_edit ("
+	query += "id INTEGER PRIMARY KEY, "
+	query += "date TEXT, "
+	query += "num TEXT, "
+	query += "field TEXT, "
+	query += "old TEXT, "
+	query += "new TEXT, "
+	query += "notes TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "member_edit", query)
+	if err != nil {
+		return fmt.Errorf("create member_edit table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to apply edits to the member record of a member number and log them, within a single transaction.
+// The old value of each edit is set from the record. Edits that do not change the value are skipped, a change
+// of status is checked against the allowed transitions of the member lifecycle. With preview set the
+// transaction is rolled back. Returns the edits applied.
+func (m *MemberEditModel) Apply(num string, edits []*models.MemberEdit, preview bool) ([]*models.MemberEdit, error) {
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	applied := []*models.MemberEdit{}
+
+	for _, e := range edits {
+
+		if !editColumn(e.Field) {
+			return nil, fmt.Errorf("edit of %s failed: %w", num, fmt.Errorf("field %q can not be edited", e.Field))
+		}
+
+		// Column names are checked above, values are passed as arguments
+		err = tx.QueryRow("SELECT IFNULL("+e.Field+", '') FROM member WHERE num = ?", num).Scan(&e.Old)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("sql query failed for %s: %w", num, errors.New("no matching record found"))
+			} else {
+				return nil, fmt.Errorf("sql query failed for %s: %w", num, err)
+			}
+		}
+
+		if e.Old == e.New {
+			continue
+		}
+
+		if e.Field == "status" {
+			err = models.Status(e.Old).Transition(models.Status(e.New))
+			if err != nil {
+				return nil, fmt.Errorf("edit of %s failed: %w", num, err)
+			}
+		}
+
+		_, err = tx.Exec("UPDATE member SET "+e.Field+" = ? WHERE num = ?", e.New, num)
+		if err != nil {
+			return nil, fmt.Errorf("update of %s failed: %w", num, err)
+		}
+
+		query := "INSERT INTO member_edit (date, num, field, old, new, notes) "
+		query += "VALUES (datetime('now', 'localtime'), ?, ?, ?, ?, ?)"
+
+		_, err = tx.Exec(query, num, e.Field, e.Old, e.New, e.Notes)
+		if err != nil {
+			return nil, fmt.Errorf("insert member_edit failed: %w", err)
+		}
+
+		e.Num = num
+		applied = append(applied, e)
+	}
+
+	if preview {
+		return applied, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit transaction failed: %w", err)
+	}
+
+	return applied, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the logged edits of the member record of a member number, most recent first
+func (m *MemberEditModel) List(num string) ([]*models.MemberEdit, error) {
+
+	query := "SELECT id, date, num, field, old, new, notes "
+	query += "FROM member_edit "
+	query += "WHERE num = ? "
+	query += "ORDER BY date DESC, id DESC"
+
+	rows, err := m.DB.Query(query, num)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	editList := []*models.MemberEdit{}
+
+	for rows.Next() {
+
+		e := &models.MemberEdit{}
+
+		err = rows.Scan(
+			&e.ID,
+			&e.Date,
+			&e.Num,
+			&e.Field,
+			&e.Old,
+			&e.New,
+			&e.Notes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("member_edit sql query failed: %w", err)
+		}
+
+		editList = append(editList, e)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return editList, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+func editColumn(field string) bool {
+
+	for _, c := range EditColumns {
+		if c == field {
+			return true
+		}
+	}
+
+	return false
+}
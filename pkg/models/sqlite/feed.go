@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"svtc-sync/pkg/models"
+)
+
+type FeedModel struct {
+	DB *sql.DB
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the feed table, that records archived versions of the actives JSON file, if it does not
+// exist yet
+func (m *FeedModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS feed ("
+	query += "id INTEGER PRIMARY KEY, "
+	query += "fetched TEXT, "
+	query += "last_modified TEXT, "
+	query += "etag TEXT, "
+	query += "checksum TEXT, "
+	query += "count INTEGER, "
+	query += "file TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "feed", query)
+	if err != nil {
+		return fmt.Errorf("create feed table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to add a record of a synced version of the actives JSON file
+func (m *FeedModel) Insert(f *models.Feed) error {
+
+	query := "INSERT INTO feed "
+	query += "(fetched, last_modified, etag, checksum, count, file) "
+	query += "VALUES (datetime('now', 'localtime'), ?, ?, ?, ?, ?)"
+
+	_, err := m.DB.Exec(query, f.LastModified, f.ETag, f.Checksum, f.Count, f.File)
+	if err != nil {
+		return fmt.Errorf("insert feed failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the most recent version of the actives JSON file. Returns nil if there is none.
+func (m *FeedModel) Latest() (*models.Feed, error) {
+
+	fl, err := m.List(1)
+	if err != nil {
+		return nil, err
+	}
+	if len(fl) == 0 {
+		return nil, nil
+	}
+
+	return fl[0], nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the record of an archived version by its checksum, or a unique prefix of it
+func (m *FeedModel) Get(checksum string) (*models.Feed, error) {
+
+	query := "SELECT id, fetched, last_modified, etag, checksum, count, file "
+	query += "FROM feed "
+	query += "WHERE checksum LIKE ? "
+	query += "ORDER BY id DESC"
+
+	rows, err := m.DB.Query(query, checksum+"%")
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+
+	fl, err := scanFeeds(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(fl) == 0:
+		return nil, sql.ErrNoRows
+	case fl[0].Checksum != fl[len(fl)-1].Checksum:
+		return nil, errors.New("checksum prefix is not unique")
+	}
+
+	return fl[0], nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the archived versions, most recent first. A limit of 0 or less returns all versions.
+func (m *FeedModel) List(limit int) ([]*models.Feed, error) {
+
+	if limit <= 0 {
+		limit = -1
+	}
+
+	query := "SELECT id, fetched, last_modified, etag, checksum, count, file "
+	query += "FROM feed "
+	query += "ORDER BY id DESC "
+	query += "LIMIT ?"
+
+	rows, err := m.DB.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+
+	return scanFeeds(rows)
+}
+
+func scanFeeds(rows *sql.Rows) ([]*models.Feed, error) {
+
+	defer rows.Close()
+
+	feedList := []*models.Feed{}
+
+	for rows.Next() {
+
+		f := &models.Feed{}
+
+		err := rows.Scan(
+			&f.ID,
+			&f.Fetched,
+			&f.LastModified,
+			&f.ETag,
+			&f.Checksum,
+			&f.Count,
+			&f.File,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("feed sql query failed: %w", err)
+		}
+
+		feedList = append(feedList, f)
+
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return feedList, nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -22,8 +22,13 @@ type MemberModel struct {
 //   - escape apostrophes (') in names e.g. "O'Connor"
 //   - date fileds (joined, expired) are expected to be "YYYY-MM-DD"
 //   - an active flag is used to indicate invalid records (set to false / "0")
+//   - the status must be one of the ClubExpress statuses
 func (m *MemberModel) Insert(member *models.MemberSVTC) error {
 
+	if !member.Status.Valid() {
+		return fmt.Errorf("insert member failed: %w: %q", models.ErrInvalidStatus, member.Status)
+	}
+
 	var flag int64
 	if member.Active {
 		flag = 1
@@ -81,7 +86,7 @@ func (m *MemberModel) Insert(member *models.MemberSVTC) error {
 // Based on the specified expire date string members will be filtered by status and expire date.
 func (m *MemberModel) ListMembers() ([]*models.MemberSVTC, error) {
 
-	query := "SELECT num, firstname, lastname, email, status, expired "
+	query := "SELECT num, firstname, lastname, email, status, IFNULL(joined, ''), expired "
 	query += "FROM member "
 	query += "WHERE active = ? "
 
@@ -103,6 +108,7 @@ func (m *MemberModel) ListMembers() ([]*models.MemberSVTC, error) {
 			&member.LastName,
 			&member.Email,
 			&member.Status,
+			&member.Joined,
 			&member.Expired,
 		)
 		if err != nil {
@@ -293,6 +299,28 @@ func (m *MemberModel) ListAlias() ([]*models.MemberSVTC, []*models.MemberAlias,
 // Based on the specified expire date string members will be filtered by status and expire date.
 func (m *MemberModel) GetAlias(search *models.MemberSVTC) ([]*models.MemberSVTC, error) {
 
+	memberList, _, err := m.queryAlias(search)
+	if err != nil {
+		return nil, err
+	}
+
+	return memberList, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the alias table with the same criteria as GetAlias, but return the matching alias records
+// along with their member records, e.g. to explain a match.
+func (m *MemberModel) ListAliasMatch(search *models.MemberSVTC) ([]*models.MemberSVTC, []*models.MemberAlias, error) {
+
+	return m.queryAlias(search)
+
+}
+
+// Query of GetAlias and ListAliasMatch, returns the matching member records and alias records in the same order
+func (m *MemberModel) queryAlias(search *models.MemberSVTC) ([]*models.MemberSVTC, []*models.MemberAlias, error) {
+
 	// Example:
 	// 		select *
 	// 		from member
@@ -302,7 +330,8 @@ func (m *MemberModel) GetAlias(search *models.MemberSVTC) ([]*models.MemberSVTC,
 	//		and member.status = 'Expired'
 	// 		and member.expired > '2001-01-31';
 
-	query := "SELECT member.num, member.firstname, member.lastname, member.email, member.status, member.expired "
+	query := "SELECT member.num, member.firstname, member.lastname, member.email, member.status, member.expired, "
+	query += "alias.firstname, alias.lastname, alias.email "
 	query += "FROM member INNER JOIN alias ON member.id = alias.memberid "
 	query += "WHERE member.active = ? "
 	query += "AND ((lower(alias.firstname) = ? AND lower(alias.lastname) = ?) OR lower(alias.email) = ?) "
@@ -317,15 +346,17 @@ func (m *MemberModel) GetAlias(search *models.MemberSVTC) ([]*models.MemberSVTC,
 
 	rows, err := m.DB.Query(query, 1, search.FirstName, search.LastName, search.Email, search.Status, search.Expired)
 	if err != nil {
-		return nil, fmt.Errorf("sql query failed: %w", err)
+		return nil, nil, fmt.Errorf("sql query failed: %w", err)
 	}
 	defer rows.Close()
 
 	memberList := []*models.MemberSVTC{}
+	aliasList := []*models.MemberAlias{}
 
 	for rows.Next() {
 
 		member := &models.MemberSVTC{}
+		alias := &models.MemberAlias{}
 
 		err = rows.Scan(
 			&member.Num,
@@ -334,25 +365,29 @@ func (m *MemberModel) GetAlias(search *models.MemberSVTC) ([]*models.MemberSVTC,
 			&member.Email,
 			&member.Status,
 			&member.Expired,
+			&alias.FirstName,
+			&alias.LastName,
+			&alias.Email,
 		)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				return nil, fmt.Errorf("member sql query failed: %w", errors.New("no matching record found"))
+				return nil, nil, fmt.Errorf("member sql query failed: %w", errors.New("no matching record found"))
 			} else {
-				return nil, fmt.Errorf("member sql query failed: %w", err)
+				return nil, nil, fmt.Errorf("member sql query failed: %w", err)
 			}
 		}
 
 		memberList = append(memberList, member)
+		aliasList = append(aliasList, alias)
 
 	}
 
 	err = rows.Err()
 	if err != nil {
-		return nil, fmt.Errorf("row iteraton error: %w", err)
+		return nil, nil, fmt.Errorf("row iteraton error: %w", err)
 	}
 
-	return memberList, nil
+	return memberList, aliasList, nil
 
 }
 
@@ -389,18 +424,19 @@ func (m *MemberModel) Get(num string) (*models.MemberSVTC, error) {
 
 // --------------------------------------------------------------------------------------------
 
-// Function to update a member's status based on their member number
-func (m *MemberModel) UpdateStatus(num, status, expired string) error {
+// Function to update a member's status based on their member number. The change of status is checked against
+// the allowed transitions of the member lifecycle, within the same transaction as the update.
+func (m *MemberModel) UpdateStatus(num string, status models.Status, expired string) error {
 
-	query := "UPDATE member SET status = ?, expired = ? WHERE num = ?"
-
-	stmt, err := m.DB.Prepare(query)
+	tx, err := m.DB.Begin()
 	if err != nil {
-		return fmt.Errorf("prepare sql query failed: %w", err)
+		return fmt.Errorf("begin transaction failed: %w", err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	result, err := stmt.Exec(status, expired, num)
+	var old models.Status
+
+	err = tx.QueryRow("SELECT status FROM member WHERE num = ?", num).Scan(&old)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("sql query failed for %s: %w", num, errors.New("no matching record found"))
@@ -409,12 +445,158 @@ func (m *MemberModel) UpdateStatus(num, status, expired string) error {
 		}
 	}
 
-	_, err = result.RowsAffected()
+	err = old.Transition(status)
+	if err != nil {
+		return fmt.Errorf("update of %s failed: %w", num, err)
+	}
+
+	_, err = tx.Exec("UPDATE member SET status = ?, expired = ? WHERE num = ?", status, expired, num)
+	if err != nil {
+		return fmt.Errorf("sql query failed for %s: %w", num, err)
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("could not get rows affected: %w", err)
+		return fmt.Errorf("commit transaction failed: %w", err)
 	}
 
 	return nil
 }
 
 // --------------------------------------------------------------------------------------------
+
+// Function to query all member records, incl. invalid records (active flag not set), with empty strings in place
+// of NULL values. Used to audit the data quality of the DB.
+func (m *MemberModel) ListAll() ([]*models.MemberSVTC, error) {
+
+	query := "SELECT id, IFNULL(num, ''), IFNULL(active, 0), IFNULL(firstname, ''), IFNULL(lastname, ''), "
+	query += "IFNULL(email, ''), IFNULL(status, ''), IFNULL(joined, ''), IFNULL(expired, '') "
+	query += "FROM member ORDER BY num"
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	memberList := []*models.MemberSVTC{}
+
+	for rows.Next() {
+
+		member := &models.MemberSVTC{}
+
+		err = rows.Scan(
+			&member.ID,
+			&member.Num,
+			&member.Active,
+			&member.FirstName,
+			&member.LastName,
+			&member.Email,
+			&member.Status,
+			&member.Joined,
+			&member.Expired,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("member sql query failed: %w", err)
+		}
+
+		memberList = append(memberList, member)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return memberList, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query all alias records, incl. those that do not refer to an existing member record
+func (m *MemberModel) ListAliases() ([]*models.MemberAlias, error) {
+
+	query := "SELECT id, IFNULL(memberid, 0), IFNULL(firstname, ''), IFNULL(lastname, ''), IFNULL(email, '') "
+	query += "FROM alias ORDER BY id"
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	aliasList := []*models.MemberAlias{}
+
+	for rows.Next() {
+
+		alias := &models.MemberAlias{}
+
+		err = rows.Scan(
+			&alias.ID,
+			&alias.MemberID,
+			&alias.FirstName,
+			&alias.LastName,
+			&alias.Email,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("alias sql query failed: %w", err)
+		}
+
+		aliasList = append(aliasList, alias)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return aliasList, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to retrieve all columns of a single member record, incl. an invalid record, based on their member
+// number, with empty strings in place of NULL values
+func (m *MemberModel) GetRecord(num string) (*models.MemberSVTC, error) {
+
+	member := &models.MemberSVTC{}
+
+	query := "SELECT " + memberColumns
+	query += "FROM member "
+	query += "WHERE num = ?"
+
+	err := m.DB.QueryRow(query, num).Scan(
+		&member.ID,
+		&member.Num,
+		&member.Active,
+		&member.Login,
+		&member.FirstName,
+		&member.Middle,
+		&member.LastName,
+		&member.Email,
+		&member.Status,
+		&member.Joined,
+		&member.Expired,
+		&member.Address,
+		&member.AddrExt,
+		&member.City,
+		&member.State,
+		&member.Zip,
+		&member.Mobile,
+		&member.Phone,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		} else {
+			return nil, fmt.Errorf("member sql query failed: %w", err)
+		}
+	}
+
+	return member, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
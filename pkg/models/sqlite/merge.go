@@ -0,0 +1,254 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+type MergeModel struct {
+	DB *sql.DB
+}
+
+// Tables holding the history of a member by member number, moved to the kept record on a merge
+var mergeHistory = []string{"outreach", "action", "run_user", "member_edit"}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the member_merge table, that records merged duplicate member records, if it does not exist
+func (m *MergeModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS member_merge ("
+	query += "drop_num TEXT PRIMARY KEY, "
+	query += "keep_num TEXT, "
+	query += "date TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "member_merge", query)
+	if err != nil {
+		return fmt.Errorf("create member_merge table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to merge the member record of the drop number into the record of the keep number, within a single
+// transaction:
+//   - aliases of the dropped record, and its name and email as a new alias, are tied to the kept record
+//   - outreach, action, run and edit history is moved to the keep number
+//   - the kept record gets the earlier joined and the later expired date of both records, and the status of the
+//     record with the later expired date, if the member lifecycle allows the transition
+//   - the dropped record is marked invalid (active flag set to false / "0")
+//   - the merge is recorded, earlier merges into the drop number are redirected to the keep number
+//
+// With preview set the transaction is rolled back. Returns the number of rows changed by table.
+func (m *MergeModel) Merge(mm *models.MemberMerge, preview bool) (map[string]int64, error) {
+
+	if mm.KeepNum == mm.DropNum {
+		return nil, fmt.Errorf("merge of %s failed: %w", mm.DropNum, errors.New("member numbers are the same"))
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "SELECT id, active, IFNULL(firstname, ''), IFNULL(lastname, ''), IFNULL(email, ''), IFNULL(status, ''), IFNULL(joined, ''), IFNULL(expired, '') "
+	query += "FROM member WHERE num = ?"
+
+	records := map[string]*models.MemberSVTC{}
+	for _, num := range []string{mm.KeepNum, mm.DropNum} {
+
+		var into string
+		err = tx.QueryRow("SELECT keep_num FROM member_merge WHERE drop_num = ?", num).Scan(&into)
+		if err == nil {
+			return nil, fmt.Errorf("merge of %s failed: %w", mm.DropNum, fmt.Errorf("%s was merged into %s already", num, into))
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("sql query failed for %s: %w", num, err)
+		}
+
+		r := &models.MemberSVTC{Num: num}
+		err = tx.QueryRow(query, num).Scan(&r.ID, &r.Active, &r.FirstName, &r.LastName, &r.Email, &r.Status, &r.Joined, &r.Expired)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("sql query failed for %s: %w", num, errors.New("no matching record found"))
+			} else {
+				return nil, fmt.Errorf("sql query failed for %s: %w", num, err)
+			}
+		}
+		records[num] = r
+	}
+
+	keep, drop := records[mm.KeepNum], records[mm.DropNum]
+	if !keep.Active {
+		return nil, fmt.Errorf("merge of %s failed: %w", mm.DropNum, fmt.Errorf("record %s to keep is invalid", mm.KeepNum))
+	}
+	if !drop.Active {
+		return nil, fmt.Errorf("merge of %s failed: %w", mm.DropNum, fmt.Errorf("record %s to drop is invalid already", mm.DropNum))
+	}
+
+	changed := map[string]int64{}
+	exec := func(table string, query string, args ...interface{}) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("update %s failed: %w", table, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("could not get rows affected: %w", err)
+		}
+		changed[table] += n
+		return nil
+	}
+
+	err = exec("alias", "UPDATE alias SET memberid = ? WHERE memberid = ?", keep.ID, drop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name and email of the dropped record remain known, e.g. as the email of a Slack account
+	if !strings.EqualFold(drop.FirstName+" "+drop.LastName+" "+drop.Email, keep.FirstName+" "+keep.LastName+" "+keep.Email) {
+		query = "INSERT INTO alias (memberid, firstname, lastname, email) "
+		query += "SELECT ?, ?, ?, ? WHERE NOT EXISTS ("
+		query += "SELECT 1 FROM alias WHERE memberid = ? AND lower(firstname) = lower(?) AND lower(lastname) = lower(?) AND lower(email) = lower(?))"
+		err = exec("alias", query, keep.ID, drop.FirstName, drop.LastName, drop.Email, keep.ID, drop.FirstName, drop.LastName, drop.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, table := range mergeHistory {
+		err = exec(table, "UPDATE "+table+" SET num = ? WHERE num = ?", mm.KeepNum, mm.DropNum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	joined, expired, status := keep.Joined, keep.Expired, keep.Status
+	valid := func(date string) bool { return !helpers.GetDate(date).IsZero() }
+	if valid(drop.Joined) && (!valid(joined) || drop.Joined < joined) {
+		joined = drop.Joined
+	}
+	if valid(drop.Expired) && (!valid(expired) || drop.Expired > expired) {
+		expired = drop.Expired
+		if drop.Status != "" {
+			status = drop.Status
+		}
+	}
+
+	// The status belongs to the later expired date, e.g. Active of a re-registered member whose kept record expired
+	if status != keep.Status {
+		err = keep.Status.Transition(status)
+		if err != nil {
+			return nil, fmt.Errorf("merge of %s failed: status of %s: %w", mm.DropNum, mm.KeepNum, err)
+		}
+	}
+
+	query = "UPDATE member SET joined = ?, expired = ?, status = ? "
+	query += "WHERE id = ? AND (IFNULL(joined, '') != ? OR IFNULL(expired, '') != ? OR IFNULL(status, '') != ?)"
+	err = exec("member", query, joined, expired, status, keep.ID, joined, expired, status)
+	if err != nil {
+		return nil, err
+	}
+
+	err = exec("member", "UPDATE member SET active = 0 WHERE id = ?", drop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = exec("member_merge", "UPDATE member_merge SET keep_num = ? WHERE keep_num = ?", mm.KeepNum, mm.DropNum)
+	if err != nil {
+		return nil, err
+	}
+	err = exec("member_merge", "INSERT INTO member_merge (drop_num, keep_num, date) VALUES (?, ?, datetime('now', 'localtime'))", mm.DropNum, mm.KeepNum)
+	if err != nil {
+		return nil, err
+	}
+
+	if preview {
+		return changed, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit transaction failed: %w", err)
+	}
+
+	return changed, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the recorded merges, mapping dropped member numbers to the member numbers kept
+func (m *MergeModel) Redirects() (map[string]string, error) {
+
+	rows, err := m.DB.Query("SELECT drop_num, keep_num FROM member_merge")
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	redirects := map[string]string{}
+
+	for rows.Next() {
+
+		var drop, keep string
+
+		err = rows.Scan(&drop, &keep)
+		if err != nil {
+			return nil, fmt.Errorf("member_merge sql query failed: %w", err)
+		}
+
+		redirects[drop] = keep
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return redirects, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the recorded merges, most recent first
+func (m *MergeModel) List() ([]*models.MemberMerge, error) {
+
+	rows, err := m.DB.Query("SELECT drop_num, keep_num, date FROM member_merge ORDER BY date DESC")
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	mergeList := []*models.MemberMerge{}
+
+	for rows.Next() {
+
+		mm := &models.MemberMerge{}
+
+		err = rows.Scan(&mm.DropNum, &mm.KeepNum, &mm.Date)
+		if err != nil {
+			return nil, fmt.Errorf("member_merge sql query failed: %w", err)
+		}
+
+		mergeList = append(mergeList, mm)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return mergeList, nil
+}
+
+// --------------------------------------------------------------------------------------------
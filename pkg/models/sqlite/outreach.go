@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"svtc-sync/pkg/models"
+)
+
+type OutreachModel struct {
+	DB *sql.DB
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the outreach table if it does not exist yet. Unlike the member and alias tables, which
+// are seeded outside the scope of this tool, outreach records are created by svtc-sync itself.
+func (m *OutreachModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS outreach ("
+	query += "id INTEGER PRIMARY KEY, "
+	query += "num TEXT, "
+	query += "platform TEXT, "
+	query += "identity TEXT, "
+	query += "channel TEXT, "
+	query += "date TEXT, "
+	query += "template TEXT, "
+	query += "notes TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "outreach", query)
+	if err != nil {
+		return fmt.Errorf("create outreach table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to add an outreach record to the database. Date fields are expected to be "YYYY-MM-DD".
+func (m *OutreachModel) Insert(o *models.Outreach) error {
+
+	query := "INSERT INTO outreach "
+	query += "(num, platform, identity, channel, date, template, notes) "
+	query += "VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	stmt, err := m.DB.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("prepare sql query failed: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(o.Num, o.Platform, o.Identity, o.Channel, o.Date, o.Template, o.Notes)
+	if err != nil {
+		return fmt.Errorf("insert outreach failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query and return a list of outreach records with a date on or after the specified date string,
+// ordered by most recent first. An empty date string returns all records.
+func (m *OutreachModel) List(since string) ([]*models.Outreach, error) {
+
+	query := "SELECT id, num, platform, identity, channel, date, template, notes "
+	query += "FROM outreach "
+	query += "WHERE date >= ? "
+	query += "ORDER BY date DESC, id DESC"
+
+	rows, err := m.DB.Query(query, since)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	outreachList := []*models.Outreach{}
+
+	for rows.Next() {
+
+		o := &models.Outreach{}
+
+		err = rows.Scan(
+			&o.ID,
+			&o.Num,
+			&o.Platform,
+			&o.Identity,
+			&o.Channel,
+			&o.Date,
+			&o.Template,
+			&o.Notes,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("outreach sql query failed: %w", errors.New("no matching record found"))
+			} else {
+				return nil, fmt.Errorf("outreach sql query failed: %w", err)
+			}
+		}
+
+		outreachList = append(outreachList, o)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return outreachList, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create a table maintained by this tool, unless it exists already. The table is looked up first,
+// so that read-only commands do not write to the DB once the tables exist.
+func createTable(db *sql.DB, name string, query string) error {
+
+	var count int
+
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("sql query failed: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = db.Exec(query)
+
+	return err
+}
+
+// --------------------------------------------------------------------------------------------
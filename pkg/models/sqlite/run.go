@@ -0,0 +1,298 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"svtc-sync/pkg/models"
+)
+
+type RunModel struct {
+	DB *sql.DB
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the run and run_user tables, that hold snapshots of check results, if they do not exist
+func (m *RunModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS run ("
+	query += "id INTEGER PRIMARY KEY, "
+	query += "date TEXT, "
+	query += "platform TEXT, "
+	query += "total INTEGER"
+	query += ")"
+
+	err := createTable(m.DB, "run", query)
+	if err != nil {
+		return fmt.Errorf("create run table failed: %w", err)
+	}
+
+	query = "CREATE TABLE IF NOT EXISTS run_user ("
+	query += "run_id INTEGER REFERENCES run(id), "
+	query += "identity TEXT, "
+	query += "name TEXT, "
+	query += "num TEXT, "
+	query += "status TEXT, "
+	query += "expired TEXT"
+	query += ")"
+
+	err = createTable(m.DB, "run_user", query)
+	if err != nil {
+		return fmt.Errorf("create run_user table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to store a run snapshot and its results per platform user in a single transaction.
+// Returns the ID of the new run record.
+func (m *RunModel) Insert(run *models.Run, users []*models.RunUser) (int, error) {
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO run (date, platform, total) VALUES (?, ?, ?)", run.Date, run.Platform, run.Total)
+	if err != nil {
+		return 0, fmt.Errorf("insert run failed: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("could not get last inserted id: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO run_user (run_id, identity, name, num, status, expired) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("prepare sql query failed: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, u := range users {
+		_, err = stmt.Exec(id, u.Identity, u.Name, u.Num, u.Status, u.Expired)
+		if err != nil {
+			return 0, fmt.Errorf("insert run user failed: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("commit transaction failed: %w", err)
+	}
+
+	return int(id), nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the most recent runs of a platform, most recent first. A limit <= 0 returns all runs.
+func (m *RunModel) List(platform string, limit int) ([]*models.Run, error) {
+
+	query := "SELECT id, date, platform, total "
+	query += "FROM run "
+	query += "WHERE platform = ? "
+	query += "ORDER BY date DESC, id DESC "
+	query += "LIMIT ?"
+
+	// Sqlite interprets a negative limit as no limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := m.DB.Query(query, platform, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	runList := []*models.Run{}
+
+	for rows.Next() {
+
+		run := &models.Run{}
+
+		err = rows.Scan(
+			&run.ID,
+			&run.Date,
+			&run.Platform,
+			&run.Total,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("run sql query failed: %w", err)
+		}
+
+		runList = append(runList, run)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return runList, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the results per platform user of a run
+func (m *RunModel) Users(runID int) ([]*models.RunUser, error) {
+
+	query := "SELECT run_id, identity, name, num, status, expired "
+	query += "FROM run_user "
+	query += "WHERE run_id = ?"
+
+	rows, err := m.DB.Query(query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	userList := []*models.RunUser{}
+
+	for rows.Next() {
+
+		u := &models.RunUser{}
+
+		err = rows.Scan(
+			&u.RunID,
+			&u.Identity,
+			&u.Name,
+			&u.Num,
+			&u.Status,
+			&u.Expired,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("run user sql query failed: %w", errors.New("no matching record found"))
+			} else {
+				return nil, fmt.Errorf("run user sql query failed: %w", err)
+			}
+		}
+
+		userList = append(userList, u)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return userList, nil
+
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the distinct member numbers that were matched with the given status in any run, e.g. to
+// determine all members that were seen on a trial membership
+func (m *RunModel) Nums(status string) ([]string, error) {
+
+	query := "SELECT DISTINCT num FROM run_user WHERE status = ? AND num != '' ORDER BY num"
+
+	rows, err := m.DB.Query(query, status)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	nl := []string{}
+
+	for rows.Next() {
+		var num string
+		err = rows.Scan(&num)
+		if err != nil {
+			return nil, fmt.Errorf("run_user sql query failed: %w", err)
+		}
+		nl = append(nl, num)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return nl, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the date of the most recent run of a platform, in which each platform user was matched with
+// the given status, by identity, e.g. to determine when a user was last seen as an Active member
+func (m *RunModel) LastMatched(platform string, status string) (map[string]string, error) {
+
+	query := "SELECT u.identity, MAX(r.date) "
+	query += "FROM run_user u JOIN run r ON r.id = u.run_id "
+	query += "WHERE r.platform = ? AND u.status = ? "
+	query += "GROUP BY u.identity"
+
+	rows, err := m.DB.Query(query, platform, status)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	matched := map[string]string{}
+
+	for rows.Next() {
+		var identity, date string
+		err = rows.Scan(&identity, &date)
+		if err != nil {
+			return nil, fmt.Errorf("run_user sql query failed: %w", err)
+		}
+		matched[identity] = date
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return matched, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the statuses that each member number was matched with in the runs of all platforms, in the
+// order of the runs, e.g. to determine the lapses of a member
+func (m *RunModel) Statuses() (map[string][]string, error) {
+
+	query := "SELECT u.num, u.status "
+	query += "FROM run_user u JOIN run r ON r.id = u.run_id "
+	query += "WHERE u.num != '' "
+	query += "ORDER BY r.date, r.id"
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := map[string][]string{}
+
+	for rows.Next() {
+		var num, status string
+		err = rows.Scan(&num, &status)
+		if err != nil {
+			return nil, fmt.Errorf("run_user sql query failed: %w", err)
+		}
+		statuses[num] = append(statuses[num], status)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// --------------------------------------------------------------------------------------------
@@ -0,0 +1,494 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"svtc-sync/pkg/helpers"
+	"svtc-sync/pkg/models"
+)
+
+// Columns of the member table that can be searched and sorted by
+var SearchColumns = append([]string{"num"}, EditColumns...)
+
+var ErrSearchSyntax = errors.New("invalid search expression")
+
+// Columns of a full member record, with empty strings in place of NULL values
+const memberColumns = "id, num, IFNULL(active, 0), IFNULL(login, ''), IFNULL(firstname, ''), IFNULL(middle, ''), " +
+	"IFNULL(lastname, ''), IFNULL(email, ''), IFNULL(status, ''), IFNULL(joined, ''), IFNULL(expired, ''), " +
+	"IFNULL(address, ''), IFNULL(addr_ext, ''), IFNULL(city, ''), IFNULL(state, ''), IFNULL(zip, ''), " +
+	"IFNULL(mobile, ''), IFNULL(phone, '') "
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the member records that match a search expression, e.g.
+//
+//	status in (Expired, Trial) and expired >= 2023-01-01 and city = "San Jose"
+//
+// The expression is compiled to a parameterized WHERE clause, see compileSearch. Only valid records are
+// searched, unless the expression refers to the active flag. Records are sorted by a comma separated list of
+// columns, each prefixed with "-" for descending order (default member number), and limited to the given number
+// of records (0 for no limit).
+func (m *MemberModel) Search(expr string, sort string, limit int) ([]*models.MemberSVTC, error) {
+
+	where, args, err := compileSearch(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + memberColumns
+	query += "FROM member "
+	query += "WHERE " + where + " "
+
+	order, err := compileSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	query += "ORDER BY " + order + " "
+
+	if limit > 0 {
+		query += "LIMIT ? "
+		args = append(args, limit)
+	}
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	memberList := []*models.MemberSVTC{}
+
+	for rows.Next() {
+
+		member := &models.MemberSVTC{}
+
+		err = rows.Scan(
+			&member.ID,
+			&member.Num,
+			&member.Active,
+			&member.Login,
+			&member.FirstName,
+			&member.Middle,
+			&member.LastName,
+			&member.Email,
+			&member.Status,
+			&member.Joined,
+			&member.Expired,
+			&member.Address,
+			&member.AddrExt,
+			&member.City,
+			&member.State,
+			&member.Zip,
+			&member.Mobile,
+			&member.Phone,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("member sql query failed: %w", err)
+		}
+
+		memberList = append(memberList, member)
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("row iteraton error: %w", err)
+	}
+
+	return memberList, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Compile a search expression to a WHERE clause with ? placeholders and its arguments. The grammar is
+//
+//	expr       = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expr ")" | comparison
+//	comparison = column ( op value | [ "not" ] "in" "(" value { "," value } ")" | [ "not" ] "like" value )
+//	op         = "=" | "!=" | "<>" | "<" | "<=" | ">" | ">="
+//
+// Keywords and text comparisons are case insensitive. Values containing spaces or operator characters must be
+// quoted with " or '. The member number is compared as a number, dates must be of the form YYYY-MM-DD, status
+// codes (e.g. EXP) stand for their status, and * is the wildcard of like. An empty expression matches all valid
+// records.
+func compileSearch(expr string) (string, []interface{}, error) {
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	p := &searchParser{tokens: tokens}
+	if len(tokens) == 0 {
+		return "active = 1", nil, nil
+	}
+
+	where, err := p.expr()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return "", nil, p.errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	if !p.active {
+		where = "active = 1 AND (" + where + ")"
+	}
+
+	return where, p.args, nil
+}
+
+// Compile a comma separated list of columns, each prefixed with "-" for descending order, to an ORDER BY clause
+func compileSort(sort string) (string, error) {
+
+	order := []string{}
+	for _, s := range strings.Split(sort, ",") {
+
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+
+		dir := "ASC"
+		if strings.HasPrefix(s, "-") {
+			dir = "DESC"
+			s = s[1:]
+		}
+
+		col, ok := searchColumn(s)
+		if !ok {
+			return "", fmt.Errorf("invalid sort column %q, expected one of %s", s, strings.Join(SearchColumns, ", "))
+		}
+		order = append(order, col+" "+dir)
+	}
+
+	// Tie break by member number for a stable order, e.g. with a limit
+	order = append(order, "CAST(num AS INTEGER) ASC")
+
+	return strings.Join(order, ", "), nil
+}
+
+// SQL expression of a searchable column, false if the column is not searchable
+func searchColumn(name string) (string, bool) {
+
+	for _, c := range SearchColumns {
+		if c != name {
+			continue
+		}
+		switch c {
+		case "num":
+			return "CAST(num AS INTEGER)", true
+		case "active":
+			return "IFNULL(active, 0)", true
+		}
+		return "IFNULL(" + c + ", '') COLLATE NOCASE", true
+	}
+
+	return "", false
+}
+
+// --------------------------------------------------------------------------------------------
+
+type searchToken struct {
+	text   string
+	quoted bool // Quoted value, never a keyword or operator
+	pos    int  // Position in the expression, for error messages
+}
+
+// Split a search expression into words, quoted values, operators and punctuation
+func tokenize(expr string) ([]*searchToken, error) {
+
+	tokens := []*searchToken{}
+	rs := []rune(expr)
+
+	for i := 0; i < len(rs); {
+
+		r := rs[i]
+		switch {
+
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, &searchToken{text: string(r), pos: i})
+			i++
+
+		case strings.ContainsRune("=!<>", r):
+			j := i + 1
+			if j < len(rs) && strings.ContainsRune("=>", rs[j]) {
+				j++
+			}
+			op := string(rs[i:j])
+			switch op {
+			case "=", "!=", "<>", "<", "<=", ">", ">=":
+			default:
+				return nil, fmt.Errorf("%w: position %d: unknown operator %q", ErrSearchSyntax, i+1, op)
+			}
+			tokens = append(tokens, &searchToken{text: op, pos: i})
+			i = j
+
+		case r == '"' || r == '\'':
+			// Quoted value, a doubled quote stands for the quote itself
+			s := []rune{}
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == r {
+					if j+1 < len(rs) && rs[j+1] == r {
+						s = append(s, r)
+						j++
+						continue
+					}
+					break
+				}
+				s = append(s, rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("%w: position %d: unterminated quote", ErrSearchSyntax, i+1)
+			}
+			tokens = append(tokens, &searchToken{text: string(s), quoted: true, pos: i})
+			i = j + 1
+
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune("(),=!<>\"'", rs[j]) {
+				j++
+			}
+			tokens = append(tokens, &searchToken{text: string(rs[i:j]), pos: i})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Recursive descent parser of a search expression, emitting SQL as it goes
+type searchParser struct {
+	tokens []*searchToken
+	pos    int
+	args   []interface{}
+	active bool // The expression refers to the active flag
+}
+
+func (p *searchParser) errorf(format string, a ...interface{}) error {
+
+	at := "end of expression"
+	if p.pos < len(p.tokens) {
+		at = fmt.Sprintf("position %d", p.tokens[p.pos].pos+1)
+	}
+
+	return fmt.Errorf("%w: %s: %s", ErrSearchSyntax, at, fmt.Sprintf(format, a...))
+}
+
+// Next token if it is the given keyword or punctuation (case insensitive), which is consumed
+func (p *searchParser) accept(text string) bool {
+
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, text) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *searchParser) next() (*searchToken, error) {
+
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("incomplete expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	return t, nil
+}
+
+func (p *searchParser) expr() (string, error) {
+
+	s, err := p.term()
+	if err != nil {
+		return "", err
+	}
+	for p.accept("or") {
+		t, err := p.term()
+		if err != nil {
+			return "", err
+		}
+		s += " OR " + t
+	}
+
+	return s, nil
+}
+
+func (p *searchParser) term() (string, error) {
+
+	s, err := p.factor()
+	if err != nil {
+		return "", err
+	}
+	for p.accept("and") {
+		f, err := p.factor()
+		if err != nil {
+			return "", err
+		}
+		s += " AND " + f
+	}
+
+	return s, nil
+}
+
+func (p *searchParser) factor() (string, error) {
+
+	if p.accept("not") {
+		f, err := p.factor()
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + f, nil
+	}
+
+	if p.accept("(") {
+		s, err := p.expr()
+		if err != nil {
+			return "", err
+		}
+		if !p.accept(")") {
+			return "", p.errorf("expected )")
+		}
+		return "(" + s + ")", nil
+	}
+
+	return p.comparison()
+}
+
+func (p *searchParser) comparison() (string, error) {
+
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	name := strings.ToLower(t.text)
+	if t.quoted {
+		p.pos--
+		return "", p.errorf("expected a column, not a value")
+	}
+	col, ok := searchColumn(name)
+	if !ok {
+		p.pos--
+		return "", p.errorf("unknown column %q, expected one of %s", t.text, strings.Join(SearchColumns, ", "))
+	}
+	if name == "active" {
+		p.active = true
+	}
+
+	not := ""
+	if p.accept("not") {
+		not = "NOT "
+	}
+
+	switch {
+
+	case p.accept("in"):
+		if !p.accept("(") {
+			return "", p.errorf("expected ( after in")
+		}
+		ph := []string{}
+		for {
+			err = p.value(name, false)
+			if err != nil {
+				return "", err
+			}
+			ph = append(ph, "?")
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return "", p.errorf("expected , or )")
+			}
+		}
+		return col + " " + not + "IN (" + strings.Join(ph, ", ") + ")", nil
+
+	case p.accept("like"):
+		err = p.value(name, true)
+		if err != nil {
+			return "", err
+		}
+		return col + " " + not + "LIKE ?", nil
+
+	case not != "":
+		return "", p.errorf("expected in or like after not")
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	switch op.text {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		if op.quoted {
+			p.pos--
+			return "", p.errorf("expected an operator")
+		}
+	default:
+		p.pos--
+		return "", p.errorf("expected an operator, in or like after %s", name)
+	}
+
+	err = p.value(name, false)
+	if err != nil {
+		return "", err
+	}
+
+	return col + " " + op.text + " ?", nil
+}
+
+// Parse and check a value of a column, and add it to the arguments
+func (p *searchParser) value(name string, like bool) error {
+
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if !t.quoted && (t.text == "(" || t.text == ")" || t.text == ",") {
+		p.pos--
+		return p.errorf("expected a value")
+	}
+	v := t.text
+
+	if like {
+		p.args = append(p.args, strings.ReplaceAll(v, "*", "%"))
+		return nil
+	}
+
+	switch name {
+
+	case "num", "active":
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			p.pos--
+			return p.errorf("%s %q is not a number", name, v)
+		}
+		p.args = append(p.args, n)
+		return nil
+
+	case "joined", "expired":
+		if helpers.GetDate(v).IsZero() {
+			p.pos--
+			return p.errorf("%s date %q is not of the form YYYY-MM-DD", name, v)
+		}
+
+	case "status":
+		if s, ok := models.StatusMap[strings.ToUpper(v)]; ok {
+			v = string(s)
+		}
+	}
+
+	p.args = append(p.args, v)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// SQL expressions of the columns used in the search tests
+const (
+	sqlStatus = "IFNULL(status, '') COLLATE NOCASE"
+	sqlCity   = "IFNULL(city, '') COLLATE NOCASE"
+	sqlState  = "IFNULL(state, '') COLLATE NOCASE"
+	sqlLast   = "IFNULL(lastname, '') COLLATE NOCASE"
+	sqlEmail  = "IFNULL(email, '') COLLATE NOCASE"
+)
+
+func TestCompileSearch(t *testing.T) {
+
+	for _, tc := range []struct {
+		expr  string
+		where string
+		args  []interface{}
+	}{
+		// Empty expression and the active flag
+		{"", "active = 1", nil},
+		{"active = 0", "IFNULL(active, 0) = ?", []interface{}{0}},
+
+		// Precedence of and, or and not
+		{"city = a or city = b and state = CA",
+			"active = 1 AND (" + sqlCity + " = ? OR " + sqlCity + " = ? AND " + sqlState + " = ?)",
+			[]interface{}{"a", "b", "CA"}},
+		{"(city = a or city = b) and state = CA",
+			"active = 1 AND ((" + sqlCity + " = ? OR " + sqlCity + " = ?) AND " + sqlState + " = ?)",
+			[]interface{}{"a", "b", "CA"}},
+		{"not city = a and state = CA",
+			"active = 1 AND (NOT " + sqlCity + " = ? AND " + sqlState + " = ?)",
+			[]interface{}{"a", "CA"}},
+		{"NOT (city = a OR state = CA)",
+			"active = 1 AND (NOT (" + sqlCity + " = ? OR " + sqlState + " = ?))",
+			[]interface{}{"a", "CA"}},
+
+		// In and like
+		{"status in (EXP, Trial)", "active = 1 AND (" + sqlStatus + " IN (?, ?))", []interface{}{"Expired", "Trial"}},
+		{"status not in (ACT)", "active = 1 AND (" + sqlStatus + " NOT IN (?))", []interface{}{"Active"}},
+		{"email like *@example.com", "active = 1 AND (" + sqlEmail + " LIKE ?)", []interface{}{"%@example.com"}},
+		{"lastname not like 'O''*'", "active = 1 AND (" + sqlLast + " NOT LIKE ?)", []interface{}{"O'%"}},
+
+		// Quoted values with spaces and quotes
+		{`city = "San Jose"`, "active = 1 AND (" + sqlCity + " = ?)", []interface{}{"San Jose"}},
+		{`lastname = 'O''Brien'`, "active = 1 AND (" + sqlLast + " = ?)", []interface{}{"O'Brien"}},
+		{`lastname = "say ""hi"" and or"`, "active = 1 AND (" + sqlLast + " = ?)", []interface{}{`say "hi" and or`}},
+		{`city = "("`, "active = 1 AND (" + sqlCity + " = ?)", []interface{}{"("}},
+
+		// Status codes, numbers and dates
+		{"status = exp", "active = 1 AND (" + sqlStatus + " = ?)", []interface{}{"Expired"}},
+		{"status != Active", "active = 1 AND (" + sqlStatus + " != ?)", []interface{}{"Active"}},
+		{"num >= 1000", "active = 1 AND (CAST(num AS INTEGER) >= ?)", []interface{}{1000}},
+		{"expired<2023-01-01 AND joined>=2015-01-01",
+			"active = 1 AND (IFNULL(expired, '') COLLATE NOCASE < ? AND IFNULL(joined, '') COLLATE NOCASE >= ?)",
+			[]interface{}{"2023-01-01", "2015-01-01"}},
+	} {
+		where, args, err := compileSearch(tc.expr)
+		if err != nil {
+			t.Errorf("%q: %s", tc.expr, err)
+			continue
+		}
+		if where != tc.where {
+			t.Errorf("%q: where\n\t%s\nexpected\n\t%s", tc.expr, where, tc.where)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("%q: args %#v, expected %#v", tc.expr, args, tc.args)
+		}
+	}
+}
+
+func TestCompileSearchErrors(t *testing.T) {
+
+	for _, expr := range []string{
+		// Unknown columns
+		"foo = 1",
+		"id = 1",
+		`"status" = EXP`,
+
+		// Unbalanced parentheses
+		"(status = EXP",
+		"status = EXP)",
+		"((status = EXP) or city = a",
+		"status in (EXP, TRI",
+
+		// Trailing and missing operators
+		"status = EXP and",
+		"status = EXP or",
+		"not",
+		"status =",
+		"status EXP",
+		"status not = EXP",
+		"num == 1",
+
+		// Malformed values
+		`city = "San Jose`,
+		"num = abc",
+		"expired >= 2023",
+		"status in ()",
+	} {
+		where, args, err := compileSearch(expr)
+		if !errors.Is(err, ErrSearchSyntax) {
+			t.Errorf("%q: compiled to %q %v, expected a syntax error, got %v", expr, where, args, err)
+		}
+	}
+}
+
+func TestCompileSort(t *testing.T) {
+
+	order, err := compileSort("-expired, lastname")
+	if err != nil {
+		t.Fatalf("compileSort: %s", err)
+	}
+	want := "IFNULL(expired, '') COLLATE NOCASE DESC, IFNULL(lastname, '') COLLATE NOCASE ASC, CAST(num AS INTEGER) ASC"
+	if order != want {
+		t.Errorf("order %q, expected %q", order, want)
+	}
+
+	_, err = compileSort("id")
+	if err == nil {
+		t.Errorf("compileSort of unknown column: expected error")
+	}
+}
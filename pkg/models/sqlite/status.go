@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"svtc-sync/pkg/models"
+)
+
+type ExpressStatusModel struct {
+	DB *sql.DB
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to create the express_status table, that caches results of the ClubExpress member status api by
+// email, if it does not exist
+func (m *ExpressStatusModel) Init() error {
+
+	query := "CREATE TABLE IF NOT EXISTS express_status ("
+	query += "email TEXT PRIMARY KEY, "
+	query += "code INTEGER, "
+	query += "checked TEXT"
+	query += ")"
+
+	err := createTable(m.DB, "express_status", query)
+	if err != nil {
+		return fmt.Errorf("create express_status table failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to store the result of a status lookup, replacing an earlier result for the same email
+func (m *ExpressStatusModel) Upsert(s *models.ExpressStatus) error {
+
+	query := "INSERT OR REPLACE INTO express_status (email, code, checked) "
+	query += "VALUES (?, ?, datetime('now', 'localtime'))"
+
+	_, err := m.DB.Exec(query, strings.ToLower(strings.TrimSpace(s.Email)), s.Code)
+	if err != nil {
+		return fmt.Errorf("insert express_status failed: %w", err)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------------------------------------------------
+
+// Function to query the cached result of a status lookup by email, that is not older than the given number of
+// days. Returns nil if there is none.
+func (m *ExpressStatusModel) Get(email string, days int) (*models.ExpressStatus, error) {
+
+	query := "SELECT email, code, checked FROM express_status "
+	query += "WHERE email = ? AND checked >= datetime('now', 'localtime', ?)"
+
+	s := &models.ExpressStatus{}
+
+	err := m.DB.QueryRow(query, strings.ToLower(strings.TrimSpace(email)), fmt.Sprintf("-%d days", days)).Scan(
+		&s.Email,
+		&s.Code,
+		&s.Checked,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("express_status sql query failed: %w", err)
+	}
+
+	return s, nil
+}
+
+// --------------------------------------------------------------------------------------------
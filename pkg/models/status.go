@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Membership status of a member record, as used by ClubExpress
+type Status string
+
+const (
+	StatusActive      Status = "Active"
+	StatusExpired     Status = "Expired"
+	StatusDropped     Status = "Dropped"
+	StatusFrozen      Status = "Frozen"
+	StatusBulkLoaded  Status = "Bulk Loaded"
+	StatusPending     Status = "Pending"
+	StatusProspective Status = "Prospective"
+	StatusTrial       Status = "Trial"
+)
+
+// Error of a member record write with a status that is not known
+var ErrInvalidStatus = errors.New("invalid status")
+
+// Error of a member record write with a status change that is not allowed
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// Statuses by their output option, e.g. -out EXP
+var StatusMap = map[string]Status{
+	"ACT": StatusActive,
+	"EXP": StatusExpired,
+	"DRO": StatusDropped,
+	"FRO": StatusFrozen,
+	"BUL": StatusBulkLoaded,
+	"PEN": StatusPending,
+	"PRO": StatusProspective,
+	"TRI": StatusTrial,
+}
+
+// Statuses by the return code of the ClubExpress member status api
+var StatusCodes = map[int]Status{
+	1:  StatusActive,
+	2:  StatusExpired,
+	3:  StatusDropped,
+	5:  StatusFrozen,
+	6:  StatusBulkLoaded,
+	7:  StatusPending,
+	8:  StatusProspective,
+	10: StatusTrial,
+}
+
+// Allowed transitions of the member lifecycle, by status before the change. Members join as Prospective,
+// Pending, Trial or Active (or were Bulk Loaded), memberships expire and are renewed, and are eventually
+// dropped; a dropped member may rejoin.
+var transitions = map[Status][]Status{
+	StatusProspective: {StatusPending, StatusTrial, StatusActive, StatusDropped},
+	StatusPending:     {StatusTrial, StatusActive, StatusExpired, StatusDropped},
+	StatusBulkLoaded:  {StatusPending, StatusTrial, StatusActive, StatusExpired, StatusDropped},
+	StatusTrial:       {StatusPending, StatusActive, StatusExpired, StatusDropped},
+	StatusActive:      {StatusFrozen, StatusExpired, StatusDropped},
+	StatusFrozen:      {StatusActive, StatusExpired, StatusDropped},
+	StatusExpired:     {StatusPending, StatusActive, StatusDropped},
+	StatusDropped:     {StatusProspective, StatusPending, StatusTrial, StatusActive},
+}
+
+// Valid reports whether the status is one of the ClubExpress statuses
+func (s Status) Valid() bool {
+
+	_, ok := transitions[s]
+
+	return ok
+}
+
+// Transition checks that a member record may change from status s to status to. Keeping the status is always
+// allowed. Records with an unknown status, e.g. of an earlier version of the DB, may change to any status, so
+// that they can be corrected.
+func (s Status) Transition(to Status) error {
+
+	if !to.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, to)
+	}
+	if s == to || !s.Valid() {
+		return nil
+	}
+
+	for _, t := range transitions[s] {
+		if t == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, s, to)
+}
+
+// Returns the output options of all statuses, e.g. ACT, in alphabetical order
+func StatusOptions() []string {
+
+	ol := make([]string, 0, len(StatusMap))
+	for o := range StatusMap {
+		ol = append(ol, o)
+	}
+	sort.Strings(ol)
+
+	return ol
+}